@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/anatoliyv/cleanurl/pkg/cleanurl"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// stdinName is the input name that stands for stdin.
+const stdinName = "-"
+
+// expandInputs returns the inputs named by the positional arguments and the
+// --files-from list, in order, with glob patterns expanded. Without any
+// inputs, stdin is read.
+func expandInputs(args []string, filesFrom string) ([]string, error) {
+	names := append([]string{}, args...)
+
+	if filesFrom != "" {
+		listed, err := readFileList(filesFrom)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, listed...)
+	}
+
+	if len(names) == 0 {
+		return []string{stdinName}, nil
+	}
+
+	var inputs []string
+	for _, name := range names {
+		if name == stdinName || !strings.ContainsAny(name, "*?[") {
+			inputs = append(inputs, name)
+			continue
+		}
+		matches, err := filepath.Glob(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", name, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("no files match %q", name)
+		}
+		inputs = append(inputs, matches...)
+	}
+	return inputs, nil
+}
+
+// readFileList reads input names from list ("-" for stdin), one per line.
+// Blank lines and lines starting with "#" are skipped.
+func readFileList(list string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if list != stdinName {
+		f, err := os.Open(list)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var names []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			names = append(names, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", list, err)
+	}
+	return names, nil
+}
+
+// Magic numbers of the supported compression formats
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// openInput opens name ("-" for stdin) and transparently decompresses gzip,
+// bzip2, zstd and xz data, detected by its magic number rather than by the
+// file extension so that compressed stdin works too.
+func openInput(name string) (io.ReadCloser, error) {
+	var f io.ReadCloser = io.NopCloser(os.Stdin)
+	if name != stdinName {
+		file, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		f = file
+	}
+
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(len(xzMagic))
+
+	var r io.Reader
+	var err error
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		r, err = gzip.NewReader(br)
+	case bytes.HasPrefix(magic, bzip2Magic) && len(magic) > 3 && '1' <= magic[3] && magic[3] <= '9':
+		r = bzip2.NewReader(br)
+	case bytes.HasPrefix(magic, zstdMagic):
+		var d *zstd.Decoder
+		d, err = zstd.NewReader(br)
+		if err == nil {
+			r = d.IOReadCloser()
+		}
+	case bytes.HasPrefix(magic, xzMagic):
+		r, err = xz.NewReader(br)
+	default:
+		r = br
+	}
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return &inputFile{Reader: r, file: f}, nil
+}
+
+// inputFile closes the decompressor, if any, and the underlying file.
+type inputFile struct {
+	io.Reader
+	file io.Closer
+}
+
+func (f *inputFile) Close() error {
+	if c, ok := f.Reader.(io.Closer); ok {
+		c.Close()
+	}
+	return f.file.Close()
+}
+
+// parseBase parses the --base URL, which must be absolute.
+func parseBase(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return nil, fmt.Errorf("invalid --base %q: must be an absolute URL", raw)
+	}
+	return u, nil
+}
+
+// warnings receives the reports of skipped lines.
+var warnings io.Writer = os.Stderr
+
+// eachURL calls fn with the URLs of the named inputs, in order: every
+// non-empty line, trimmed of surrounding whitespace, or with --extract the
+// URLs found anywhere in the lines. Relative references are resolved
+// against --base. Lines longer than --max-line-length are skipped with a
+// warning.
+func eachURL(names []string, fn func(url string) error) error {
+	for _, name := range names {
+		if err := eachURLOf(name, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func eachURLOf(name string, fn func(url string) error) error {
+	f, err := openInput(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if name == stdinName {
+		name = "stdin"
+	}
+
+	// Each input is its own document for <base href>
+	extractor := cleanurl.Extractor{Base: baseURL}
+
+	lines := newLineReader(f, maxLineLength)
+	for {
+		line, err := lines.Next()
+		if err == io.EOF {
+			return nil
+		}
+		var tooLong *lineTooLongError
+		if errors.As(err, &tooLong) {
+			fmt.Fprintf(warnings, "Warning: %s:%d: skipped line of %d bytes (--max-line-length is %d)\n", name, tooLong.line, tooLong.length, maxLineLength)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", name, err)
+		}
+
+		urls := []string{cleanurl.ResolveReference(baseURL, strings.TrimSpace(line))}
+		if extract {
+			urls = extractor.Extract(line)
+		}
+		for _, url := range urls {
+			if url == "" {
+				continue
+			}
+			if err := fn(url); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// readInputs reads the URLs of the named inputs, in order.
+func readInputs(names []string) ([]string, error) {
+	urls := []string{}
+	err := eachURL(names, func(url string) error {
+		urls = append(urls, url)
+		return nil
+	})
+	return urls, err
+}
+
+// lineTooLongError reports a line longer than the lineReader maximum.
+type lineTooLongError struct {
+	line   int
+	length int
+}
+
+func (e *lineTooLongError) Error() string {
+	return fmt.Sprintf("line %d is %d bytes long", e.line, e.length)
+}
+
+// lineReader reads lines of any length, where bufio.Scanner gives up at
+// 64 KiB. With max > 0, longer lines are discarded while they are read, so
+// they are never held in memory, and reported as a *lineTooLongError.
+type lineReader struct {
+	r    *bufio.Reader
+	max  int
+	line int
+}
+
+func newLineReader(r io.Reader, max int) *lineReader {
+	return &lineReader{r: bufio.NewReader(r), max: max}
+}
+
+// Next returns the next line without its line ending, or io.EOF at the end
+// of the input.
+func (lr *lineReader) Next() (string, error) {
+	var buf []byte
+	length := 0
+	tooLong := false
+
+	for {
+		chunk, err := lr.r.ReadSlice('\n')
+		length += len(chunk)
+		if !tooLong {
+			buf = append(buf, chunk...)
+			// Leave room for the "\r\n" line ending
+			if lr.max > 0 && len(buf) > lr.max+2 {
+				tooLong = true
+				buf = nil
+			}
+		}
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err == io.EOF && length == 0 {
+			return "", io.EOF
+		}
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+		break
+	}
+
+	lr.line++
+	if tooLong {
+		return "", &lineTooLongError{line: lr.line, length: length - len("\n")}
+	}
+	line := strings.TrimSuffix(strings.TrimSuffix(string(buf), "\n"), "\r")
+	if lr.max > 0 && len(line) > lr.max {
+		return "", &lineTooLongError{line: lr.line, length: len(line)}
+	}
+	return line, nil
+}
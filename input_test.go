@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/ulikunitz/xz"
+)
+
+// bzip2URL is "https://bz2.example.com\n" compressed with bzip2, as the
+// standard library can only decompress it.
+var bzip2URL = []byte{
+	0x42, 0x5a, 0x68, 0x39, 0x31, 0x41, 0x59, 0x26, 0x53, 0x59, 0x7b, 0x74, 0xa9, 0x9d, 0x00, 0x00,
+	0x06, 0x59, 0x80, 0x00, 0x10, 0x00, 0x01, 0x90, 0x10, 0x3a, 0x46, 0xcc, 0x50, 0x20, 0x00, 0x22,
+	0x9a, 0x19, 0x1a, 0x0c, 0xca, 0x14, 0xd3, 0x23, 0x13, 0x13, 0x13, 0x8e, 0xac, 0xc6, 0x4d, 0xde,
+	0x13, 0x60, 0x7a, 0x60, 0xaa, 0x10, 0x7e, 0x2e, 0xe4, 0x8a, 0x70, 0xa1, 0x20, 0xf6, 0xe9, 0x53,
+	0x3a,
+}
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+func TestExpandInputs(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, filepath.Join(dir, "a.txt"), nil)
+	b := writeFile(t, filepath.Join(dir, "b.txt"), nil)
+	gz := writeFile(t, filepath.Join(dir, "c.gz"), nil)
+	list := writeFile(t, filepath.Join(dir, "list"), []byte("# inputs\n"+gz+"\n\n"+filepath.Join(dir, "*.txt")+"\n"))
+
+	tests := []struct {
+		name      string
+		args      []string
+		filesFrom string
+		expected  []string
+		wantErr   bool
+	}{
+		{
+			name:     "No inputs reads stdin",
+			expected: []string{"-"},
+		},
+		{
+			name:     "Files and stdin in order",
+			args:     []string{b, "-", a},
+			expected: []string{b, "-", a},
+		},
+		{
+			name:     "Glob expansion",
+			args:     []string{filepath.Join(dir, "*.txt")},
+			expected: []string{a, b},
+		},
+		{
+			name:      "Files from list after arguments",
+			args:      []string{a},
+			filesFrom: list,
+			expected:  []string{a, gz, a, b},
+		},
+		{
+			name:    "Glob without matches",
+			args:    []string{filepath.Join(dir, "*.xz")},
+			wantErr: true,
+		},
+		{
+			name:      "Missing list",
+			filesFrom: filepath.Join(dir, "missing"),
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := expandInputs(tt.args, tt.filesFrom)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestReadInputs(t *testing.T) {
+	dir := t.TempDir()
+
+	var gzipped bytes.Buffer
+	gw := gzip.NewWriter(&gzipped)
+	gw.Write([]byte("https://gz.example.com\n"))
+	gw.Close()
+
+	var zstded bytes.Buffer
+	zw, _ := zstd.NewWriter(&zstded)
+	zw.Write([]byte("https://zst.example.com\n"))
+	zw.Close()
+
+	var xzed bytes.Buffer
+	xw, _ := xz.NewWriter(&xzed)
+	xw.Write([]byte("https://xz.example.com\n"))
+	xw.Close()
+
+	plain := writeFile(t, filepath.Join(dir, "plain.txt"), []byte("  https://plain.example.com  \n\nhttps://last.example.com"))
+	gz := writeFile(t, filepath.Join(dir, "urls.gz"), gzipped.Bytes())
+	bz2 := writeFile(t, filepath.Join(dir, "urls.bz2"), bzip2URL)
+	zst := writeFile(t, filepath.Join(dir, "urls.zst"), zstded.Bytes())
+	xzFile := writeFile(t, filepath.Join(dir, "urls.xz"), xzed.Bytes())
+	// Compression is detected from the content, not the extension
+	misnamed := writeFile(t, filepath.Join(dir, "urls.txt"), gzipped.Bytes())
+
+	tests := []struct {
+		name     string
+		inputs   []string
+		expected []string
+	}{
+		{
+			name:     "Plain file without trailing newline is not joined to the next",
+			inputs:   []string{plain, plain},
+			expected: []string{"https://plain.example.com", "https://last.example.com", "https://plain.example.com", "https://last.example.com"},
+		},
+		{
+			name:     "Compressed files",
+			inputs:   []string{gz, bz2, zst, xzFile},
+			expected: []string{"https://gz.example.com", "https://bz2.example.com", "https://zst.example.com", "https://xz.example.com"},
+		},
+		{
+			name:     "Compressed file with another extension",
+			inputs:   []string{misnamed},
+			expected: []string{"https://gz.example.com"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := readInputs(tt.inputs)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestReadInputsErrors(t *testing.T) {
+	dir := t.TempDir()
+	corrupt := writeFile(t, filepath.Join(dir, "corrupt.gz"), []byte{0x1f, 0x8b, 0x00})
+
+	_, err := readInputs([]string{filepath.Join(dir, "missing.txt")})
+	assert.Error(t, err)
+
+	_, err = readInputs([]string{corrupt})
+	assert.Error(t, err)
+}
+
+func TestLineReader(t *testing.T) {
+	long := "https://example.com/?q=" + strings.Repeat("a", 100*1024)
+
+	tests := []struct {
+		name     string
+		input    string
+		max      int
+		expected []string
+		tooLong  []int
+	}{
+		{
+			name:     "Lines longer than bufio.Scanner's limit",
+			input:    "https://a.com\n" + long + "\nhttps://b.com",
+			expected: []string{"https://a.com", long, "https://b.com"},
+		},
+		{
+			name:     "CRLF line endings",
+			input:    "https://a.com\r\nhttps://b.com\r\n",
+			expected: []string{"https://a.com", "https://b.com"},
+		},
+		{
+			name:     "Lines over the maximum are reported",
+			input:    "https://a.com\n" + long + "\nhttps://bb.com\r\nhttps://c.com\n",
+			max:      13,
+			expected: []string{"https://a.com", "https://c.com"},
+			tooLong:  []int{2, 3},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := newLineReader(strings.NewReader(tt.input), tt.max)
+			var result []string
+			var tooLong []int
+			for {
+				line, err := lines.Next()
+				if err == io.EOF {
+					break
+				}
+				var lineErr *lineTooLongError
+				if errors.As(err, &lineErr) {
+					tooLong = append(tooLong, lineErr.line)
+					continue
+				}
+				assert.NoError(t, err)
+				result = append(result, line)
+			}
+			assert.Equal(t, tt.expected, result)
+			assert.Equal(t, tt.tooLong, tooLong)
+		})
+	}
+}
+
+func TestReadInputsMaxLineLength(t *testing.T) {
+	name := writeFile(t, filepath.Join(t.TempDir(), "urls.txt"), []byte("https://a.com\nhttps://"+strings.Repeat("b", 100)+".com\n"))
+
+	var warned bytes.Buffer
+	defer func(w io.Writer, max int) { warnings, maxLineLength = w, max }(warnings, maxLineLength)
+	warnings, maxLineLength = &warned, 64
+
+	result, err := readInputs([]string{name})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://a.com"}, result)
+	assert.Equal(t, "Warning: "+name+":2: skipped line of 112 bytes (--max-line-length is 64)\n", warned.String())
+}
+
+func TestReadInputsExtract(t *testing.T) {
+	name := writeFile(t, filepath.Join(t.TempDir(), "page.html"), []byte(`<p>Read <a href="https://example.com/docs/">the docs</a>.</p>
+<script>var api = {"base":"https:\/\/api.example.com\/v1"};</script>
+no links here
+Mirror: https://mirror.example.com/docs, or https://example.com/docs.`))
+
+	defer func(e bool) { extract = e }(extract)
+	extract = true
+
+	result, err := readInputs([]string{name})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/docs/", "https://api.example.com/v1", "https://mirror.example.com/docs", "https://example.com/docs"}, result)
+}
+
+func TestReadInputsBase(t *testing.T) {
+	dir := t.TempDir()
+	list := writeFile(t, filepath.Join(dir, "urls.txt"), []byte("/api/v1/users\n//cdn.example.com/a.js\nexample.org/path\nhttps://other.com/\n"))
+	page := writeFile(t, filepath.Join(dir, "page.html"), []byte(`<base href="https://docs.example.org/v2/">
+<a href="intro.html">Intro</a>`))
+	page2 := writeFile(t, filepath.Join(dir, "page2.html"), []byte(`<a href="../img/x.png">`))
+
+	defer func(e bool, b *url.URL) { extract, baseURL = e, b }(extract, baseURL)
+	var err error
+	baseURL, err = parseBase("https://example.com/page/")
+	assert.NoError(t, err)
+
+	result, err := readInputs([]string{list})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://example.com/api/v1/users", "https://cdn.example.com/a.js", "example.org/path", "https://other.com/"}, result)
+
+	// The base tag of one page does not apply to the next
+	extract = true
+	result, err = readInputs([]string{page, page2})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"https://docs.example.org/v2/intro.html", "https://example.com/img/x.png"}, result)
+}
+
+func TestParseBase(t *testing.T) {
+	_, err := parseBase("https://example.com/page/")
+	assert.NoError(t, err)
+
+	for _, invalid := range []string{"/page/", "example.com", "https://exa mple.com/"} {
+		_, err := parseBase(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
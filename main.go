@@ -3,260 +3,608 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 
+	"github.com/anatoliyv/cleanurl/pkg/cleanurl"
 	"github.com/spf13/cobra"
 )
 
 var (
 	// Flags
-	characters bool
-	cleanHTTP  bool
-	backslash  bool
-	lower      bool
-	onlyDomains bool
+	opts          = cleanurl.DefaultOptions()
+	onlyDomains   bool
+	subdomains    bool
+	labelsOnly    bool
+	stream        bool
+	format        string
+	columns       []string
+	filesFrom     string
+	maxLineLength int
+	extract       bool
+	base          string
+	baseURL       *url.URL
+	trackingFiles []string
+	matchSpecs    []string
+	filterSpecs   []string
+	scopeFile     string
+	oosFile       string
+	showRejected  string
+	stateFile     string
+	memoryLimit   string
 )
 
 var rootCmd = &cobra.Command{
-	Use:   "cleanurl",
-	Short: "Clean and deduplicate URLs from stdin",
-	Long: `CleanURL is a command-line tool that processes URLs from stdin and applies various cleaning operations.
+	Use:   "cleanurl [file...]",
+	Short: "Clean and deduplicate URLs from files or stdin",
+	Long: `CleanURL is a command-line tool that processes URLs from files or stdin and applies various cleaning operations.
 
 Features:
-- Convert URLs to lowercase for consistent processing
+- Convert scheme and host to lowercase for consistent processing (--lower=all for the whole URL)
 - Remove unnecessary characters (quotes and exclamation marks) from URLs
 - Remove HTTP duplicates when HTTPS version exists
+- Strip tracking query parameters such as utm_* and fbclid (--strip-tracking, --tracking-rules)
+- Keep one URL per path and parameter-name set for scanning (--dedupe-params, --keep)
+- Filter static assets by file extension or category (--exclude-ext, --include-ext, --drop)
+- Keep or drop URLs by regexp on the URL or a component (--match, --filter)
+- Restrict URLs to a program scope of hosts, wildcards, CIDR ranges and paths (--scope, --out-of-scope)
+- Collapse IDs, UUIDs, hashes, dates and slugs in paths into patterns (--collapse-paths, --patterns)
+- Rewrite queries into a canonical form (--sort-query, --drop-empty-params, --drop-empty-query, --percent-spaces)
 - Remove trailing slashes to deduplicate URLs
+- Deduplicate case-insensitively while keeping original spellings (--dedupe-key)
+- Apply RFC 3986 normalization to parsed URLs (--normalize)
 - Extract unique domain names from URLs (--only-domains)
+- Extract unique registrable domains such as example.co.uk (--root-domains)
+- List hosts grouped under each registrable domain (--subdomains, --under)
+- Output JSON, JSONL, CSV or TSV records with parsed components (--format, --columns)
+- Extract URLs from free-form text, HTML, JavaScript and JSON (--extract)
+- Resolve relative links against a base URL or the page's <base href> (--base)
+- Read files, globs and --files-from lists, decompressing .gz, .bz2, .zst and .xz
+- Output only URLs not seen in previous runs (--state)
+- Stream large inputs without loading them into memory (--stream)
+- Deduplicate inputs larger than memory with sorted runs on disk (--memory-limit)
+- Deduplicate endless streams in bounded memory with a Bloom filter (--approx-dedupe, --fp-rate, --expected-count)
 - Output cleaned URLs to stdout
 
 Examples:
   echo "https://example.com/" | cleanurl
   cat urls.txt | cleanurl --no-characters
   echo "http://example.com" | cleanurl --no-clean-http
-  echo "https://example.com/path" | cleanurl --only-domains`,
-	Run: runCleanURL,
+  echo "https://example.com/path" | cleanurl --only-domains
+  cat urls.txt | cleanurl --under example.com --labels
+  cat urls.txt | cleanurl --drop images,fonts,styles,media
+  cat urls.txt | cleanurl --scope scope.txt --out-of-scope oos.txt --show-rejected rejected.txt
+  curl -s https://example.com/page/ | cleanurl --extract --base https://example.com/page/
+  cleanurl --stream 'crawl/*.txt.gz'
+  cleanurl --memory-limit 2G 'archive/*.txt.zst'
+  tail -F feed.log | cleanurl --extract --approx-dedupe
+  cat today.txt | cleanurl --state seen.db
+  find crawl -name '*.zst' | cleanurl --files-from -`,
+	// Input files are positional arguments alongside the set commands
+	Args: cobra.ArbitraryArgs,
+	Run:  runCleanURL,
 }
 
 func init() {
 	// Set default values to true (on by default)
-	rootCmd.Flags().BoolVar(&characters, "characters", true, "Remove unnecessary characters (quotes and exclamation marks) from URLs")
-	rootCmd.Flags().BoolVar(&cleanHTTP, "clean-http", true, "Remove HTTP duplicates when HTTPS version exists")
-	rootCmd.Flags().BoolVar(&backslash, "backslash", true, "Remove trailing slashes to deduplicate URLs")
-	rootCmd.Flags().BoolVar(&lower, "lower", true, "Convert URLs to lowercase")
+	rootCmd.Flags().BoolVar(&opts.Characters, "characters", true, "Remove unnecessary characters (quotes and exclamation marks) from URLs")
+	rootCmd.Flags().BoolVar(&opts.CleanHTTP, "clean-http", true, "Remove HTTP duplicates when HTTPS version exists")
+	rootCmd.Flags().BoolVar(&opts.Backslash, "backslash", true, "Remove trailing slashes to deduplicate URLs")
+	rootCmd.Flags().Var(&opts.Lower, "lower", "Convert URLs to lowercase: host (scheme and host only), all, or none")
+	rootCmd.Flags().Lookup("lower").NoOptDefVal = cleanurl.LowerHost.String()
+	rootCmd.Flags().BoolVar(&opts.StripTracking, "strip-tracking", false, "Remove tracking query parameters such as utm_*, fbclid and gclid")
+	rootCmd.Flags().StringArrayVar(&trackingFiles, "tracking-rules", nil, "Add tracking parameter rules from this file (implies --strip-tracking; repeatable)")
+	rootCmd.Flags().BoolVar(&opts.SortQuery, "sort-query", false, "Sort query parameters by name so that reordered queries deduplicate")
+	rootCmd.Flags().BoolVar(&opts.DropEmptyParams, "drop-empty-params", false, "Remove query parameters with an empty value (a=)")
+	rootCmd.Flags().BoolVar(&opts.DropEmptyQuery, "drop-empty-query", false, "Remove a trailing ? with no query after it")
+	rootCmd.Flags().BoolVar(&opts.PercentSpaces, "percent-spaces", false, "Write spaces in queries as %20 instead of +")
+	rootCmd.Flags().BoolVar(&opts.DedupeParams, "dedupe-params", false, "Keep one URL per scheme, host, path and set of query parameter names, ignoring values")
+	rootCmd.Flags().BoolVar(&opts.CollapsePaths, "collapse-paths", false, "Deduplicate by path pattern, treating numeric IDs, UUIDs, hashes, dates and slugs as placeholders")
+	rootCmd.Flags().Var(&opts.Keep, "keep", "With --dedupe-params or --collapse-paths, the URL to keep: first, last, shortest or longest")
+	rootCmd.Flags().BoolVar(&opts.Patterns, "patterns", false, "With --dedupe-params or --collapse-paths, print the pattern instead of a sample URL")
+	rootCmd.Flags().StringSliceVar(&opts.ExcludeExt, "exclude-ext", nil, "Drop URLs whose path has one of these file extensions (query strings are ignored)")
+	rootCmd.Flags().StringSliceVar(&opts.IncludeExt, "include-ext", nil, "Keep only URLs whose path has one of these file extensions")
+	rootCmd.Flags().StringSliceVar(&opts.Drop, "drop", nil, "Drop URLs of these categories: images, fonts, styles, media, docs")
+	rootCmd.Flags().StringArrayVar(&matchSpecs, "match", nil, "Keep only URLs matching this regexp, optionally prefixed with host:, path:, query:, param:name: etc. (repeatable)")
+	rootCmd.Flags().StringArrayVar(&filterSpecs, "filter", nil, "Drop URLs matching this regexp, with the same component prefixes as --match (repeatable)")
+	rootCmd.Flags().StringVar(&scopeFile, "scope", "", "Keep only URLs whose host (and path) matches a rule in this scope file")
+	rootCmd.Flags().StringVar(&oosFile, "out-of-scope", "", "Drop URLs whose host (and path) matches a rule in this scope file")
+	rootCmd.Flags().StringVar(&showRejected, "show-rejected", "", "Write the URLs dropped by --scope or --out-of-scope to this file (- for stderr)")
+	rootCmd.Flags().BoolVar(&opts.Normalize, "normalize", false, "Parse URLs and apply RFC 3986 normalization (scheme/host case, percent-encoding, dot segments, default ports)")
+	rootCmd.Flags().BoolVar(&opts.DedupeKey, "dedupe-key", false, "Deduplicate case-insensitively but output the first original spelling of each URL")
 	rootCmd.Flags().BoolVar(&onlyDomains, "only-domains", false, "Extract only unique domain names from URLs")
+	rootCmd.Flags().BoolVar(&opts.RootDomains, "root-domains", false, "Extract only unique registrable domains (eTLD+1) using the Public Suffix List")
+	rootCmd.Flags().BoolVar(&opts.PrivateSuffixRoots, "private-roots", false, "With --root-domains, report private suffixes such as github.io as roots")
+	rootCmd.Flags().BoolVar(&subdomains, "subdomains", false, "List unique hosts grouped under each registrable domain")
+	rootCmd.Flags().StringVar(&opts.Under, "under", "", "With --subdomains, list only hosts below this domain")
+	rootCmd.Flags().BoolVar(&labelsOnly, "labels", false, "With --subdomains, print only the label prefix (api, dev.api) of each host")
+	rootCmd.Flags().StringVar(&stateFile, "state", "", "Output only URLs not seen in previous runs with this state file, and add them to it")
+	rootCmd.Flags().BoolVar(&stream, "stream", false, "Emit URLs as they are read instead of loading all input into memory")
+	rootCmd.Flags().StringVar(&memoryLimit, "memory-limit", "", "Keep about this much URL data in memory (such as 512M or 2G) and sort the rest on disk, keeping the output of a normal run")
+	rootCmd.Flags().BoolVar(&opts.ApproxDedupe, "approx-dedupe", false, "Stream with a fixed-size Bloom filter instead of exact deduplication, dropping an occasional unique URL (implies --stream)")
+	rootCmd.Flags().Float64Var(&opts.FPRate, "fp-rate", cleanurl.DefaultFPRate, "With --approx-dedupe, the false-positive rate once --expected-count URLs are seen (implies --approx-dedupe)")
+	rootCmd.Flags().IntVar(&opts.ExpectedCount, "expected-count", cleanurl.DefaultExpectedCount, "With --approx-dedupe, the number of distinct URLs the filters are sized for (implies --approx-dedupe)")
+	rootCmd.Flags().StringVar(&format, "format", formatText, "Output format: text, json, jsonl, csv or tsv (all but text include parsed components)")
+	rootCmd.Flags().StringVar(&filesFrom, "files-from", "", "Read input file names, one per line, from this file (- for stdin)")
+	rootCmd.Flags().BoolVar(&extract, "extract", false, "Find URLs anywhere in the input (text, HTML, JavaScript, JSON) instead of reading one per line")
+	rootCmd.Flags().StringVar(&base, "base", "", "Resolve relative and protocol-relative references against this URL (an HTML <base href> overrides it)")
+	rootCmd.Flags().IntVar(&maxLineLength, "max-line-length", 0, "Skip input lines longer than this many bytes, with a warning (0 for no limit)")
+	rootCmd.Flags().StringSliceVar(&columns, "columns", nil, "CSV/TSV columns: original,url,scheme,host,port,path,query,fragment,rules")
 	
 	// Add negative flags for convenience
 	rootCmd.Flags().Bool("no-characters", false, "Disable character cleaning")
 	rootCmd.Flags().Bool("no-clean-http", false, "Disable HTTP cleaning")
 	rootCmd.Flags().Bool("no-backslash", false, "Disable backslash cleaning")
 	rootCmd.Flags().Bool("no-lower", false, "Disable lowercase conversion")
+
+	rootCmd.MarkFlagsMutuallyExclusive("stream", "subdomains")
+	rootCmd.MarkFlagsMutuallyExclusive("stream", "memory-limit")
+
+	addSetCommands(rootCmd)
 }
 
 func runCleanURL(cmd *cobra.Command, args []string) {
+	defer closeOutputs()
+	prepareOptions(cmd)
+
+	if cmd.Flag("fp-rate").Changed || cmd.Flag("expected-count").Changed {
+		opts.ApproxDedupe = true
+	}
+	if opts.ApproxDedupe {
+		if !(opts.FPRate > 0 && opts.FPRate < 1) {
+			fail(fmt.Errorf("invalid --fp-rate %g: must be between 0 and 1", opts.FPRate))
+		}
+		if opts.ExpectedCount < 1 {
+			fail(fmt.Errorf("invalid --expected-count %d: must be positive", opts.ExpectedCount))
+		}
+		if subdomains || opts.Under != "" {
+			fail(fmt.Errorf("--approx-dedupe is not supported with --subdomains"))
+		}
+		if memoryLimit != "" {
+			fail(fmt.Errorf("--approx-dedupe is not supported with --memory-limit"))
+		}
+		stream = true
+	}
+
+	if stream && opts.Keep != cleanurl.KeepFirst {
+		fail(fmt.Errorf("--keep %s is not supported with --stream, which keeps the first URL", opts.Keep))
+	}
+	if stream && opts.Under != "" {
+		fail(fmt.Errorf("--under is not supported with --stream"))
+	}
+	if labelsOnly && !subdomains && opts.Under == "" {
+		fail(fmt.Errorf("--labels is only supported with --subdomains or --under"))
+	}
+
+	domainsOnly := onlyDomains || opts.RootDomains || subdomains || opts.Under != ""
+	if format != formatText && domainsOnly {
+		fail(fmt.Errorf("--format %s is only supported for URL output", format))
+	}
+	if stateFile != "" && domainsOnly {
+		fail(fmt.Errorf("--state is only supported for URL output"))
+	}
+	var limit int64
+	if memoryLimit != "" {
+		var err error
+		if limit, err = parseSize(memoryLimit); err != nil {
+			fail(fmt.Errorf("invalid --memory-limit: %w", err))
+		}
+		if domainsOnly {
+			fail(fmt.Errorf("--memory-limit is only supported for URL output"))
+		}
+	}
+	if showRejected != "" {
+		rejected, err := newRejectedWriter(showRejected)
+		if err != nil {
+			fail(err)
+		}
+		closers = append(closers, rejected.Close)
+		opts.OnOutOfScope = rejected.Write
+	}
+
+	inputs, err := expandInputs(args, filesFrom)
+	if err != nil {
+		fail(err)
+	}
+	out, err := newResultWriter(os.Stdout, format, columns)
+	if err != nil {
+		fail(err)
+	}
+	closers = append(closers, out.Close)
+
+	if stateFile != "" {
+		seen, err := cleanurl.OpenSeenSet(stateFile)
+		if err != nil {
+			fail(err)
+		}
+		defer seen.Close()
+		opts.Seen = seen
+	}
+
+	cleaner := cleanurl.New(opts)
+
+	if stream {
+		s := cleaner.NewStream()
+		if onlyDomains || opts.RootDomains {
+			s = cleaner.NewDomainStream()
+		}
+		if err := streamURLs(inputs, out, s); err != nil {
+			fail(err)
+		}
+		if filters := s.Filters(); filters != nil {
+			reportFilters(warnings, filters)
+		}
+		saveState()
+		return
+	}
+
+	if limit > 0 {
+		if err := externalURLs(inputs, out, cleaner, limit); err != nil {
+			fail(err)
+		}
+		saveState()
+		return
+	}
+
+	// Read URLs from the inputs
+	urls, err := readInputs(inputs)
+	if err != nil {
+		fail(err)
+	}
+
+	if subdomains || opts.Under != "" {
+		writeSubdomains(os.Stdout, cleaner.Subdomains(urls))
+		return
+	}
+
+	// Apply cleaning operations
+	var results []cleanurl.Result
+	switch {
+	case onlyDomains || opts.RootDomains:
+		results = textResults(cleaner.Domains(urls))
+	case format == formatText:
+		results = textResults(cleaner.Clean(urls))
+	default:
+		results = cleaner.CleanResults(urls)
+	}
+
+	// Output results
+	for _, r := range results {
+		if err := out.Write(r); err != nil {
+			fail(err)
+		}
+	}
+	saveState()
+}
+
+// saveState adds the URLs output by this run to the --state file, if any.
+func saveState() {
+	if opts.Seen == nil {
+		return
+	}
+	if err := opts.Seen.Save(); err != nil {
+		fail(err)
+	}
+}
+
+// prepareOptions completes opts from the cleaning, filtering and input flags
+// shared by all commands.
+func prepareOptions(cmd *cobra.Command) {
 	// Handle negative flags
 	if cmd.Flag("no-characters").Changed {
-		characters = false
+		opts.Characters = false
 	}
 	if cmd.Flag("no-clean-http").Changed {
-		cleanHTTP = false
+		opts.CleanHTTP = false
 	}
 	if cmd.Flag("no-backslash").Changed {
-		backslash = false
+		opts.Backslash = false
 	}
 	if cmd.Flag("no-lower").Changed {
-		lower = false
+		opts.Lower = cleanurl.LowerNone
 	}
 
-	// Read URLs from stdin
-	urls := readURLsFromStdin()
-	
-	// Apply cleaning operations
-	var cleanedURLs []string
-	if onlyDomains {
-		cleanedURLs = extractUniqueDomains(urls)
-	} else {
-		cleanedURLs = cleanURLs(urls)
+	for _, category := range opts.Drop {
+		if _, ok := cleanurl.CategoryExtensions(category); !ok {
+			fail(fmt.Errorf("invalid --drop category %q (want images, fonts, styles, media or docs)", category))
+		}
 	}
-	
-	// Output results
-	for _, url := range cleanedURLs {
-		fmt.Println(url)
+	if len(trackingFiles) > 0 {
+		rules, err := loadTrackingRules(trackingFiles)
+		if err != nil {
+			fail(err)
+		}
+		opts.TrackingRules = rules
+		opts.StripTracking = true
+	}
+
+	var err error
+	if opts.Match, err = parseMatchers(matchSpecs); err != nil {
+		fail(err)
+	}
+	if opts.Exclude, err = parseMatchers(filterSpecs); err != nil {
+		fail(err)
+	}
+	if opts.Scope, err = loadScope(scopeFile); err != nil {
+		fail(err)
+	}
+	if opts.OutOfScope, err = loadScope(oosFile); err != nil {
+		fail(err)
+	}
+	if base != "" {
+		if baseURL, err = parseBase(base); err != nil {
+			fail(err)
+		}
 	}
 }
 
-func readURLsFromStdin() []string {
-	var urls []string
-	scanner := bufio.NewScanner(os.Stdin)
-	
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" {
-			urls = append(urls, line)
+// textResults wraps plain output lines in results for the text format.
+func textResults(lines []string) []cleanurl.Result {
+	results := make([]cleanurl.Result, 0, len(lines))
+	for _, line := range lines {
+		results = append(results, cleanurl.Result{URL: line})
+	}
+	return results
+}
+
+// parseMatchers parses the --match or --filter expressions.
+func parseMatchers(specs []string) ([]*cleanurl.Matcher, error) {
+	var matchers []*cleanurl.Matcher
+	for _, spec := range specs {
+		m, err := cleanurl.ParseMatcher(spec)
+		if err != nil {
+			return nil, err
 		}
+		matchers = append(matchers, m)
 	}
-	
-	if len(urls) == 0 {
-		return []string{}
+	return matchers, nil
+}
+
+// loadScope reads the scope file name, if any.
+func loadScope(name string) (*cleanurl.Scope, error) {
+	if name == "" {
+		return nil, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
 	}
-	return urls
+	defer f.Close()
+
+	scope, err := cleanurl.ParseScope(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return scope, nil
+}
+
+// rejectedWriter writes each URL dropped by the scope to the --show-rejected
+// file ("-" for stderr) once.
+type rejectedWriter struct {
+	f    *os.File // nil for stderr
+	w    *bufio.Writer
+	seen map[string]bool
 }
 
-func cleanURLs(urls []string) []string {
-	if len(urls) == 0 {
-		return []string{}
+func newRejectedWriter(name string) (*rejectedWriter, error) {
+	if name == stdinName {
+		return &rejectedWriter{w: bufio.NewWriter(os.Stderr), seen: make(map[string]bool)}, nil
 	}
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	return &rejectedWriter{f: f, w: bufio.NewWriter(f), seen: make(map[string]bool)}, nil
+}
 
-	// Step 1: Convert to lowercase
-	if lower {
-		urls = convertToLowercase(urls)
+func (r *rejectedWriter) Write(url string) {
+	if !r.seen[url] {
+		r.seen[url] = true
+		fmt.Fprintln(r.w, url)
 	}
+}
 
-	// Step 2: Remove unnecessary characters
-	if characters {
-		urls = removeUnnecessaryCharacters(urls)
+func (r *rejectedWriter) Close() error {
+	err := r.w.Flush()
+	if r.f == nil {
+		return err
+	}
+	if err != nil {
+		r.f.Close()
+		return err
 	}
+	return r.f.Close()
+}
 
-	// Step 2: Create maps for tracking
-	urlMap := make(map[string]bool)
-	httpsMap := make(map[string]bool)
-	var result []string
+// loadTrackingRules returns the built-in tracking rules extended with the
+// rules of each file.
+func loadTrackingRules(files []string) (*cleanurl.TrackingRules, error) {
+	rules := cleanurl.DefaultTrackingRules()
+	for _, name := range files {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		err = rules.Load(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return rules, nil
+}
+
+// closers are the outputs that closeOutputs closes, in reverse order, at the
+// end of a run. fail closes them too, so that what was written before the
+// error is not lost in a buffer.
+var closers []func() error
 
-	// First pass: collect HTTPS URLs
-	for _, url := range urls {
-		// Track HTTPS URLs by their normalized form
-		if strings.HasPrefix(url, "https://") {
-			normalized := normalizeURLForComparison(url)
-			httpsMap[normalized] = true
+// closeOutputs closes the outputs and fails on the first error.
+func closeOutputs() {
+	if err := closeAll(); err != nil {
+		fail(err)
+	}
+}
+
+func closeAll() error {
+	pending := closers
+	closers = nil
+	var first error
+	for i := len(pending) - 1; i >= 0; i-- {
+		if err := pending[i](); err != nil && first == nil {
+			first = err
 		}
 	}
-	
-	// Second pass: process URLs
-	for _, url := range urls {
-		processedURL := url
-		shouldAdd := true
-
-		// Handle trailing slashes first
-		if backslash {
-			noSlash := strings.TrimSuffix(url, "/")
-			if url != noSlash {
-				processedURL = noSlash // Always remove trailing slash
-			}
+	return first
+}
+
+// fail reports err and exits with a non-zero status.
+func fail(err error) {
+	fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	// A broken output usually fails again on close
+	if cerr := closeAll(); cerr != nil && cerr.Error() != err.Error() {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", cerr)
+	}
+	os.Exit(1)
+}
+
+// writeSubdomains prints each root domain followed by its indented hosts.
+// With --under there is a single known root, so only the hosts are printed.
+func writeSubdomains(w io.Writer, groups []cleanurl.SubdomainGroup) {
+	for _, group := range groups {
+		hosts := group.Hosts
+		if labelsOnly {
+			hosts = group.Labels()
 		}
 
-		// Handle HTTP/HTTPS duplicates (check the processed URL)
-		if cleanHTTP && strings.HasPrefix(processedURL, "http://") {
-			normalized := normalizeURLForComparison(processedURL)
-			if httpsMap[normalized] {
-				shouldAdd = false // Skip HTTP if HTTPS exists
-			}
+		indent := ""
+		if opts.Under == "" {
+			fmt.Fprintln(w, group.Root)
+			indent = "  "
+		}
+		for _, host := range hosts {
+			fmt.Fprintln(w, indent+host)
+		}
+	}
+}
+
+// streamURLs pushes every URL of the inputs through s and writes each emitted URL to
+// out as soon as it is known, followed by the URLs deferred until end of input.
+func streamURLs(inputs []string, out *resultWriter, s *cleanurl.Stream) error {
+	// Components are only parsed when the output format needs them
+	push := s.PushResult
+	flush := s.FlushResults
+	if out.format == formatText {
+		push = func(line string) (cleanurl.Result, bool) {
+			url, ok := s.Push(line)
+			return cleanurl.Result{URL: url}, ok
+		}
+		flush = func() []cleanurl.Result {
+			return textResults(s.Flush())
 		}
+	}
 
-		// Add to result if not already processed and should be added
-		if shouldAdd && !urlMap[processedURL] {
-			urlMap[processedURL] = true
-			result = append(result, processedURL)
+	err := eachURL(inputs, func(url string) error {
+		if result, ok := push(url); ok {
+			return out.Write(result)
 		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	return result
+	for _, result := range flush() {
+		if err := out.Write(result); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func convertToLowercase(urls []string) []string {
-	if len(urls) == 0 {
-		return []string{}
+// reportFilters describes the --approx-dedupe filters once the input is done.
+// The first holds every key; a URL is dropped if any filter errs.
+func reportFilters(w io.Writer, filters []*cleanurl.BloomFilter) {
+	count, size, passed := filters[0].Count(), 0, 1.0
+	for _, filter := range filters {
+		size += filter.Size()
+		passed *= 1 - filter.EstimatedFPRate()
 	}
-	var result []string
-	for _, url := range urls {
-		result = append(result, strings.ToLower(url))
+	fmt.Fprintf(w, "Approximate dedupe: %d keys in %s of filters, estimated false-positive rate %.2g (sized for %d keys at %g)\n",
+		count, formatSize(int64(size)), 1-passed, opts.ExpectedCount, opts.FPRate)
+	if count > opts.ExpectedCount {
+		fmt.Fprintf(w, "Warning: more keys than --expected-count; raise it to keep the false-positive rate at %g\n", opts.FPRate)
 	}
-	return result
 }
 
-func removeUnnecessaryCharacters(urls []string) []string {
-	if len(urls) == 0 {
-		return []string{}
+// formatSize formats a byte count in the largest unit parseSize accepts
+// that keeps it at least 1, such as 1.5 MiB.
+func formatSize(size int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
 	}
-	var result []string
-	for _, url := range urls {
-		cleaned := strings.Trim(url, `"'!`)
-		result = append(result, cleaned)
+	if unit == 0 {
+		return fmt.Sprintf("%d B", size)
 	}
-	return result
+	return fmt.Sprintf("%.1f %s", value, units[unit])
 }
 
-// normalizeURLForComparison removes protocol and trailing slash for HTTP/HTTPS comparison
-func normalizeURLForComparison(url string) string {
-	// Remove protocol
-	if strings.HasPrefix(url, "https://") {
-		url = strings.TrimPrefix(url, "https://")
-	} else if strings.HasPrefix(url, "http://") {
-		url = strings.TrimPrefix(url, "http://")
+// externalURLs cleans the URLs of the inputs with at most about limit bytes of
+// them in memory, spilling the rest to temporary files, and writes the
+// result to out.
+func externalURLs(inputs []string, out *resultWriter, cleaner *cleanurl.Cleaner, limit int64) error {
+	e, err := cleaner.NewExternal("", limit)
+	if err != nil {
+		return err
 	}
-	
-	// Remove trailing slash
-	url = strings.TrimSuffix(url, "/")
-	
-	return url
-}
+	defer e.Close()
 
-func extractUniqueDomains(urls []string) []string {
-	if len(urls) == 0 {
-		return []string{}
+	if err := eachURL(inputs, e.Push); err != nil {
+		return err
 	}
-	
-	domainMap := make(map[string]bool)
-	var result []string
-	
-	for _, url := range urls {
-		// Convert to lowercase first
-		url = strings.ToLower(url)
-		
-		// Remove unnecessary characters
-		url = strings.Trim(url, `"'!`)
-		
-		// Extract domain
-		domain := extractDomain(url)
-		if domain != "" && !domainMap[domain] {
-			domainMap[domain] = true
-			result = append(result, domain)
-		}
+	if out.format == formatText {
+		return e.Each(func(url string) error {
+			return out.Write(cleanurl.Result{URL: url})
+		})
 	}
-	
-	return result
+	return e.EachResult(out.Write)
 }
 
-func extractDomain(url string) string {
-	// Remove protocol
-	if strings.HasPrefix(url, "http://") {
-		url = strings.TrimPrefix(url, "http://")
-	} else if strings.HasPrefix(url, "https://") {
-		url = strings.TrimPrefix(url, "https://")
-	}
-	
-	// Remove www. prefix if present
-	if strings.HasPrefix(url, "www.") {
-		url = strings.TrimPrefix(url, "www.")
-	}
-	
-	// Get the domain part (before the first slash or path)
-	if idx := strings.Index(url, "/"); idx != -1 {
-		url = url[:idx]
+// sizeUnits are the suffixes accepted by parseSize, in powers of 1024.
+var sizeUnits = map[string]int64{
+	"":  1,
+	"B": 1,
+	"K": 1 << 10, "KB": 1 << 10, "KIB": 1 << 10,
+	"M": 1 << 20, "MB": 1 << 20, "MIB": 1 << 20,
+	"G": 1 << 30, "GB": 1 << 30, "GIB": 1 << 30,
+	"T": 1 << 40, "TB": 1 << 40, "TIB": 1 << 40,
+}
+
+// parseSize parses a positive byte count such as 512M, 1.5G or 100000.
+func parseSize(raw string) (int64, error) {
+	value := strings.TrimSpace(raw)
+	i := strings.IndexFunc(value, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(value)
 	}
-	
-	// Remove trailing slash if present
-	url = strings.TrimSuffix(url, "/")
-	
-	// Remove port if present (everything after the last colon)
-	if idx := strings.LastIndex(url, ":"); idx != -1 {
-		url = url[:idx]
+
+	unit, ok := sizeUnits[strings.ToUpper(strings.TrimSpace(value[i:]))]
+	n, err := strconv.ParseFloat(value[:i], 64)
+	if !ok || err != nil || n*float64(unit) < 1 {
+		return 0, fmt.Errorf("%q is not a size such as 512M or 2G", raw)
 	}
-	
-	return url
+	return int64(n * float64(unit)), nil
 }
 
-
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
@@ -1,227 +1,17 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/anatoliyv/cleanurl/pkg/cleanurl"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestConvertToLowercase(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    []string
-		expected []string
-	}{
-		{
-			name:     "Convert mixed case URLs",
-			input:    []string{"HTTPS://EXAMPLE.COM", "Http://Test.Com", "https://lowercase.com"},
-			expected: []string{"https://example.com", "http://test.com", "https://lowercase.com"},
-		},
-		{
-			name:     "Convert uppercase URLs",
-			input:    []string{"HTTPS://EXAMPLE.COM", "HTTP://TEST.COM"},
-			expected: []string{"https://example.com", "http://test.com"},
-		},
-		{
-			name:     "Already lowercase URLs",
-			input:    []string{"https://example.com", "http://test.com"},
-			expected: []string{"https://example.com", "http://test.com"},
-		},
-		{
-			name:     "Empty input",
-			input:    []string{},
-			expected: []string{},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := convertToLowercase(tt.input)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
-func TestRemoveUnnecessaryCharacters(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    []string
-		expected []string
-	}{
-		{
-			name:     "Remove single quotes",
-			input:    []string{"'https://example.com'", "https://test.com"},
-			expected: []string{"https://example.com", "https://test.com"},
-		},
-		{
-			name:     "Remove double quotes",
-			input:    []string{`"https://example.com"`, "https://test.com"},
-			expected: []string{"https://example.com", "https://test.com"},
-		},
-		{
-			name:     "Remove mixed quotes",
-			input:    []string{`"https://example.com"`, "'https://test.com'"},
-			expected: []string{"https://example.com", "https://test.com"},
-		},
-		{
-			name:     "Remove exclamation marks",
-			input:    []string{"!https://example.com!", "https://test.com"},
-			expected: []string{"https://example.com", "https://test.com"},
-		},
-		{
-			name:     "Remove mixed characters",
-			input:    []string{`"https://example.com"`, "'https://test.com'", "!https://another.com!"},
-			expected: []string{"https://example.com", "https://test.com", "https://another.com"},
-		},
-		{
-			name:     "No quotes to remove",
-			input:    []string{"https://example.com", "https://test.com"},
-			expected: []string{"https://example.com", "https://test.com"},
-		},
-		{
-			name:     "Empty input",
-			input:    []string{},
-			expected: []string{},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := removeUnnecessaryCharacters(tt.input)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
-
-
-func TestCleanURLs(t *testing.T) {
-	tests := []struct {
-		name        string
-		input       []string
-		characters  bool
-		cleanHTTP   bool
-		backslash   bool
-		lower       bool
-		expected    []string
-	}{
-		{
-			name:        "All features enabled",
-			input:       []string{`"https://example.com/"`, "http://example.com", "https://example.com"},
-			characters:  true,
-			cleanHTTP:   true,
-			backslash:   true,
-			lower:       true,
-			expected:    []string{"https://example.com"},
-		},
-		{
-			name:        "Characters disabled",
-			input:       []string{`"https://example.com"`, "https://test.com"},
-			characters:  false,
-			cleanHTTP:   true,
-			backslash:   true,
-			lower:       true,
-			expected:    []string{`"https://example.com"`, "https://test.com"},
-		},
-		{
-			name:        "HTTP cleaning disabled",
-			input:       []string{"http://example.com", "https://example.com"},
-			characters:  true,
-			cleanHTTP:   false,
-			backslash:   true,
-			lower:       true,
-			expected:    []string{"http://example.com", "https://example.com"},
-		},
-		{
-			name:        "Backslash cleaning disabled",
-			input:       []string{"https://example.com/", "https://example.com"},
-			characters:  true,
-			cleanHTTP:   true,
-			backslash:   false,
-			lower:       true,
-			expected:    []string{"https://example.com/", "https://example.com"},
-		},
-		{
-			name:        "Empty input",
-			input:       []string{},
-			characters:  true,
-			cleanHTTP:   true,
-			backslash:   true,
-			lower:       true,
-			expected:    []string{},
-		},
-		{
-			name:        "Complex deduplication",
-			input:       []string{`"http://example.com/"`, "https://example.com", "'https://test.com/'", "https://test.com"},
-			characters:  true,
-			cleanHTTP:   true,
-			backslash:   true,
-			lower:       true,
-			expected:    []string{"https://example.com", "https://test.com"},
-		},
-		{
-			name:        "Lowercase conversion with mixed case",
-			input:       []string{"HTTPS://EXAMPLE.COM", "Http://Test.Com", "!https://UPPERCASE.com!"},
-			characters:  true,
-			cleanHTTP:   true,
-			backslash:   true,
-			lower:       true,
-			expected:    []string{"https://example.com", "http://test.com", "https://uppercase.com"},
-		},
-		{
-			name:        "URLs with ports - HTTP/HTTPS deduplication",
-			input:       []string{"https://example.com:8080/path", "http://example.com:8080/path"},
-			characters:  true,
-			cleanHTTP:   true,
-			backslash:   true,
-			lower:       true,
-			expected:    []string{"https://example.com:8080/path"},
-		},
-		{
-			name:        "URLs with different ports - no deduplication",
-			input:       []string{"https://example.com:8080/path", "http://example.com:9090/path"},
-			characters:  true,
-			cleanHTTP:   true,
-			backslash:   true,
-			lower:       true,
-			expected:    []string{"https://example.com:8080/path", "http://example.com:9090/path"},
-		},
-		{
-			name:        "URLs with ports and trailing slashes",
-			input:       []string{"https://example.com:8080/path/", "http://example.com:8080/path/"},
-			characters:  true,
-			cleanHTTP:   true,
-			backslash:   true,
-			lower:       true,
-			expected:    []string{"https://example.com:8080/path"},
-		},
-		{
-			name:        "Complex port scenarios with special characters",
-			input:       []string{"'https://EXAMPLE.com:8080/path/'", `"http://example.com:9090/another"`, "https://www.EXAMPLE.com:443/different"},
-			characters:  true,
-			cleanHTTP:   true,
-			backslash:   true,
-			lower:       true,
-			expected:    []string{"https://example.com:8080/path", "http://example.com:9090/another", "https://www.example.com:443/different"},
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			// Set global flags
-			characters = tt.characters
-			cleanHTTP = tt.cleanHTTP
-			backslash = tt.backslash
-			lower = tt.lower
-
-			result := cleanURLs(tt.input)
-			assert.Equal(t, tt.expected, result)
-		})
-	}
-}
-
-func TestReadURLsFromStdin(t *testing.T) {
+func TestReadInputsFromStdin(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
@@ -281,7 +71,8 @@ func TestReadURLsFromStdin(t *testing.T) {
 			os.Stdin = file
 			defer file.Close()
 
-			result := readURLsFromStdin()
+			result, err := readInputs([]string{"-"})
+			assert.NoError(t, err)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -333,13 +124,11 @@ https://test.com:9090/another`
 	defer file.Close()
 
 	// Set all features enabled
-	characters = true
-	cleanHTTP = true
-	backslash = true
-	lower = true
+	cleaner := cleanurl.New(cleanurl.DefaultOptions())
 
-	urls := readURLsFromStdin()
-	result := cleanURLs(urls)
+	urls, err := readInputs([]string{"-"})
+	assert.NoError(t, err)
+	result := cleaner.Clean(urls)
 
 	assert.Equal(t, expected, result)
 }
@@ -363,6 +152,99 @@ func TestCommandLineFlags(t *testing.T) {
 	
 	onlyDomainsFlag := rootCmd.Flags().Lookup("only-domains")
 	assert.NotNil(t, onlyDomainsFlag)
+
+	rootDomainsFlag := rootCmd.Flags().Lookup("root-domains")
+	assert.NotNil(t, rootDomainsFlag)
+
+	privateRootsFlag := rootCmd.Flags().Lookup("private-roots")
+	assert.NotNil(t, privateRootsFlag)
+
+	subdomainsFlag := rootCmd.Flags().Lookup("subdomains")
+	assert.NotNil(t, subdomainsFlag)
+
+	underFlag := rootCmd.Flags().Lookup("under")
+	assert.NotNil(t, underFlag)
+
+	labelsFlag := rootCmd.Flags().Lookup("labels")
+	assert.NotNil(t, labelsFlag)
+
+	normalizeFlag := rootCmd.Flags().Lookup("normalize")
+	assert.NotNil(t, normalizeFlag)
+
+	dedupeKeyFlag := rootCmd.Flags().Lookup("dedupe-key")
+	assert.NotNil(t, dedupeKeyFlag)
+
+	streamFlag := rootCmd.Flags().Lookup("stream")
+	assert.NotNil(t, streamFlag)
+
+	formatFlag := rootCmd.Flags().Lookup("format")
+	assert.NotNil(t, formatFlag)
+
+	filesFromFlag := rootCmd.Flags().Lookup("files-from")
+	assert.NotNil(t, filesFromFlag)
+
+	extractFlag := rootCmd.Flags().Lookup("extract")
+	assert.NotNil(t, extractFlag)
+
+	baseFlag := rootCmd.Flags().Lookup("base")
+	assert.NotNil(t, baseFlag)
+
+	stripTrackingFlag := rootCmd.Flags().Lookup("strip-tracking")
+	assert.NotNil(t, stripTrackingFlag)
+
+	sortQueryFlag := rootCmd.Flags().Lookup("sort-query")
+	assert.NotNil(t, sortQueryFlag)
+
+	dedupeParamsFlag := rootCmd.Flags().Lookup("dedupe-params")
+	assert.NotNil(t, dedupeParamsFlag)
+
+	keepFlag := rootCmd.Flags().Lookup("keep")
+	assert.NotNil(t, keepFlag)
+
+	collapsePathsFlag := rootCmd.Flags().Lookup("collapse-paths")
+	assert.NotNil(t, collapsePathsFlag)
+
+	patternsFlag := rootCmd.Flags().Lookup("patterns")
+	assert.NotNil(t, patternsFlag)
+
+	excludeExtFlag := rootCmd.Flags().Lookup("exclude-ext")
+	assert.NotNil(t, excludeExtFlag)
+
+	includeExtFlag := rootCmd.Flags().Lookup("include-ext")
+	assert.NotNil(t, includeExtFlag)
+
+	dropFlag := rootCmd.Flags().Lookup("drop")
+	assert.NotNil(t, dropFlag)
+
+	matchFlag := rootCmd.Flags().Lookup("match")
+	assert.NotNil(t, matchFlag)
+
+	filterFlag := rootCmd.Flags().Lookup("filter")
+	assert.NotNil(t, filterFlag)
+
+	scopeFlag := rootCmd.Flags().Lookup("scope")
+	assert.NotNil(t, scopeFlag)
+
+	outOfScopeFlag := rootCmd.Flags().Lookup("out-of-scope")
+	assert.NotNil(t, outOfScopeFlag)
+
+	showRejectedFlag := rootCmd.Flags().Lookup("show-rejected")
+	assert.NotNil(t, showRejectedFlag)
+
+	stateFlag := rootCmd.Flags().Lookup("state")
+	assert.NotNil(t, stateFlag)
+
+	memoryLimitFlag := rootCmd.Flags().Lookup("memory-limit")
+	assert.NotNil(t, memoryLimitFlag)
+
+	approxDedupeFlag := rootCmd.Flags().Lookup("approx-dedupe")
+	assert.NotNil(t, approxDedupeFlag)
+
+	fpRateFlag := rootCmd.Flags().Lookup("fp-rate")
+	assert.NotNil(t, fpRateFlag)
+
+	expectedCountFlag := rootCmd.Flags().Lookup("expected-count")
+	assert.NotNil(t, expectedCountFlag)
 	
 	// Test that negative flags exist
 	noCharactersFlag := rootCmd.Flags().Lookup("no-characters")
@@ -378,205 +260,213 @@ func TestCommandLineFlags(t *testing.T) {
 	assert.NotNil(t, noLowerFlag)
 }
 
-func TestExtractDomain(t *testing.T) {
+func TestStreamURLs(t *testing.T) {
+	input := `"https://example.com/"
+http://example.com
+http://unique.com
+
+https://test.com
+https://test.com/`
+
+	name := writeFile(t, filepath.Join(t.TempDir(), "urls.txt"), []byte(input))
+
+	var out bytes.Buffer
+	cleaner := cleanurl.New(cleanurl.DefaultOptions())
+	err := streamURLs([]string{name}, &resultWriter{w: &out, format: formatText}, cleaner.NewStream())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com\nhttps://test.com\nhttp://unique.com\n", out.String())
+}
+
+func TestExternalURLs(t *testing.T) {
+	input := `"https://example.com/"
+http://example.com
+http://unique.com
+
+https://test.com
+https://test.com/`
+
+	name := writeFile(t, filepath.Join(t.TempDir(), "urls.txt"), []byte(input))
+
+	var out bytes.Buffer
+	cleaner := cleanurl.New(cleanurl.DefaultOptions())
+	err := externalURLs([]string{name}, &resultWriter{w: &out, format: formatText}, cleaner, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com\nhttp://unique.com\nhttps://test.com\n", out.String())
+}
+
+func TestParseSize(t *testing.T) {
 	tests := []struct {
-		name     string
 		input    string
-		expected string
+		expected int64
 	}{
-		{
-			name:     "Basic HTTPS URL",
-			input:    "https://example.com/path",
-			expected: "example.com",
-		},
-		{
-			name:     "Basic HTTP URL",
-			input:    "http://example.com/path",
-			expected: "example.com",
-		},
-		{
-			name:     "URL with port",
-			input:    "https://example.com:8080/path",
-			expected: "example.com",
-		},
-		{
-			name:     "URL with different port",
-			input:    "http://example.com:9090/path",
-			expected: "example.com",
-		},
-		{
-			name:     "URL with www prefix",
-			input:    "https://www.example.com/path",
-			expected: "example.com",
-		},
-		{
-			name:     "URL with www prefix and port",
-			input:    "https://www.example.com:8080/path",
-			expected: "example.com",
-		},
-		{
-			name:     "URL with trailing slash",
-			input:    "https://example.com/path/",
-			expected: "example.com",
-		},
-		{
-			name:     "URL with port and trailing slash",
-			input:    "https://example.com:8080/path/",
-			expected: "example.com",
-		},
-		{
-			name:     "Domain only with port",
-			input:    "https://example.com:8080",
-			expected: "example.com",
-		},
-		{
-			name:     "Domain only without port",
-			input:    "https://example.com",
-			expected: "example.com",
-		},
-		{
-			name:     "Complex path with port",
-			input:    "https://example.com:8080/path/to/resource?param=value",
-			expected: "example.com",
-		},
-		{
-			name:     "IP address with port",
-			input:    "https://192.168.1.1:8080/path",
-			expected: "192.168.1.1",
-		},
-		{
-			name:     "Empty input",
-			input:    "",
-			expected: "",
-		},
+		{input: "100000", expected: 100000},
+		{input: "512M", expected: 512 << 20},
+		{input: "2g", expected: 2 << 30},
+		{input: "1.5GiB", expected: 3 << 29},
+		{input: "64 KB", expected: 64 << 10},
 	}
-
 	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := extractDomain(tt.input)
-			assert.Equal(t, tt.expected, result)
-		})
+		size, err := parseSize(tt.input)
+		assert.NoError(t, err, tt.input)
+		assert.Equal(t, tt.expected, size, tt.input)
+	}
+
+	for _, invalid := range []string{"", "0", "M", "2X", "-1G", "0.1"} {
+		_, err := parseSize(invalid)
+		assert.Error(t, err, invalid)
 	}
 }
 
-func TestNormalizeURLForComparison(t *testing.T) {
+func TestReportFilters(t *testing.T) {
+	defer func(o cleanurl.Options) { opts = o }(opts)
+	opts.ExpectedCount, opts.FPRate = 2, 0.01
+
+	filter := cleanurl.NewBloomFilter(opts.ExpectedCount, opts.FPRate)
+	filter.Add("https://a.com")
+
+	var buf bytes.Buffer
+	reportFilters(&buf, []*cleanurl.BloomFilter{filter})
+	assert.Regexp(t, `^Approximate dedupe: 1 keys in 8 B of filters, estimated false-positive rate \S+ \(sized for 2 keys at 0.01\)\n$`, buf.String())
+
+	filter.Add("https://b.com")
+	filter.Add("https://c.com")
+	buf.Reset()
+	reportFilters(&buf, []*cleanurl.BloomFilter{filter, cleanurl.NewBloomFilter(opts.ExpectedCount, opts.FPRate)})
+	assert.Contains(t, buf.String(), "3 keys in 16 B of filters")
+	assert.Contains(t, buf.String(), "Warning: more keys than --expected-count")
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "424 B", formatSize(424))
+	assert.Equal(t, "1.5 KiB", formatSize(1536))
+	assert.Equal(t, "17.1 MiB", formatSize(17971200))
+	assert.Equal(t, "2.0 GiB", formatSize(2<<30))
+}
+
+func TestWriteSubdomains(t *testing.T) {
+	groups := []cleanurl.SubdomainGroup{
+		{Root: "example.com", Hosts: []string{"api.example.com", "dev.api.example.com"}},
+		{Root: "test.com", Hosts: []string{"cdn.test.com"}},
+	}
+
 	tests := []struct {
-		name     string
-		input    string
-		expected string
+		name       string
+		groups     []cleanurl.SubdomainGroup
+		under      string
+		labelsOnly bool
+		expected   string
 	}{
 		{
-			name:     "HTTPS URL",
-			input:    "https://example.com/path",
-			expected: "example.com/path",
+			name:     "Grouped hosts",
+			groups:   groups,
+			expected: "example.com\n  api.example.com\n  dev.api.example.com\ntest.com\n  cdn.test.com\n",
 		},
 		{
-			name:     "HTTP URL",
-			input:    "http://example.com/path",
-			expected: "example.com/path",
+			name:       "Grouped labels",
+			groups:     groups,
+			labelsOnly: true,
+			expected:   "example.com\n  api\n  dev.api\ntest.com\n  cdn\n",
 		},
 		{
-			name:     "HTTPS URL with port",
-			input:    "https://example.com:8080/path",
-			expected: "example.com:8080/path",
-		},
-		{
-			name:     "HTTP URL with port",
-			input:    "http://example.com:8080/path",
-			expected: "example.com:8080/path",
-		},
-		{
-			name:     "URL with trailing slash",
-			input:    "https://example.com/path/",
-			expected: "example.com/path",
-		},
-		{
-			name:     "URL with port and trailing slash",
-			input:    "https://example.com:8080/path/",
-			expected: "example.com:8080/path",
-		},
-		{
-			name:     "Domain only with trailing slash",
-			input:    "https://example.com/",
-			expected: "example.com",
-		},
-		{
-			name:     "Domain with port and trailing slash",
-			input:    "https://example.com:8080/",
-			expected: "example.com:8080",
-		},
-		{
-			name:     "Empty input",
-			input:    "",
-			expected: "",
+			name:       "Under a domain",
+			groups:     groups[:1],
+			under:      "example.com",
+			labelsOnly: true,
+			expected:   "api\ndev.api\n",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := normalizeURLForComparison(tt.input)
-			assert.Equal(t, tt.expected, result)
+			oldUnder, oldLabels := opts.Under, labelsOnly
+			defer func() { opts.Under, labelsOnly = oldUnder, oldLabels }()
+			opts.Under, labelsOnly = tt.under, tt.labelsOnly
+
+			var out bytes.Buffer
+			writeSubdomains(&out, tt.groups)
+			assert.Equal(t, tt.expected, out.String())
 		})
 	}
 }
 
-func TestExtractUniqueDomains(t *testing.T) {
-	tests := []struct {
-		name     string
-		input    []string
-		expected []string
-	}{
-		{
-			name:     "Basic domains",
-			input:    []string{"https://example.com/path", "https://test.com/another"},
-			expected: []string{"example.com", "test.com"},
-		},
-		{
-			name:     "Domains with ports",
-			input:    []string{"https://example.com:8080/path", "http://test.com:9090/another"},
-			expected: []string{"example.com", "test.com"},
-		},
-		{
-			name:     "Same domain with different ports",
-			input:    []string{"https://example.com:8080/path", "http://example.com:9090/another"},
-			expected: []string{"example.com"},
-		},
-		{
-			name:     "Mixed case domains",
-			input:    []string{"https://EXAMPLE.com:8080/path", "http://example.com:9090/another"},
-			expected: []string{"example.com"},
-		},
-		{
-			name:     "Domains with special characters",
-			input:    []string{"'https://example.com:8080/path'", `"http://test.com:9090/another"`},
-			expected: []string{"example.com", "test.com"},
-		},
-		{
-			name:     "Domains with www prefix",
-			input:    []string{"https://www.example.com:8080/path", "http://example.com:9090/another"},
-			expected: []string{"example.com"},
-		},
-		{
-			name:     "Complex deduplication with ports",
-			input:    []string{
-				"https://example.com:8080/path",
-				"http://example.com:8080/path",
-				"https://www.example.com:9090/another",
-				"http://test.com:8080/different",
-			},
-			expected: []string{"example.com", "test.com"},
-		},
-		{
-			name:     "Empty input",
-			input:    []string{},
-			expected: []string{},
-		},
-	}
+func TestLoadTrackingRules(t *testing.T) {
+	dir := t.TempDir()
+	custom := writeFile(t, filepath.Join(dir, "custom.txt"), []byte("sessionid\nexample.com ref\n"))
+	invalid := writeFile(t, filepath.Join(dir, "invalid.txt"), []byte("a b c\n"))
 
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := extractUniqueDomains(tt.input)
-			assert.Equal(t, tt.expected, result)
-		})
+	rules, err := loadTrackingRules([]string{custom})
+	assert.NoError(t, err)
+	assert.Equal(t, "https://example.com/?a=1", rules.Strip("https://example.com/?ref=x&a=1&sessionid=2&utm_source=y"))
+
+	_, err = loadTrackingRules([]string{invalid})
+	assert.ErrorContains(t, err, "invalid.txt: tracking rules line 1")
+
+	_, err = loadTrackingRules([]string{filepath.Join(dir, "missing.txt")})
+	assert.Error(t, err)
+}
+
+func TestParseMatchers(t *testing.T) {
+	matchers, err := parseMatchers([]string{`host:^api\.`, `param:id:^[0-9]{1,3}$`})
+	assert.NoError(t, err)
+	assert.Len(t, matchers, 2)
+	assert.True(t, matchers[1].Match("https://example.com/?id=123"))
+
+	matchers, err = parseMatchers(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, matchers)
+
+	_, err = parseMatchers([]string{`path:[`})
+	assert.Error(t, err)
+}
+
+func TestLoadScope(t *testing.T) {
+	dir := t.TempDir()
+	valid := writeFile(t, filepath.Join(dir, "scope.txt"), []byte("*.example.com\n10.0.0.0/8\n"))
+	invalid := writeFile(t, filepath.Join(dir, "invalid.txt"), []byte("example.com\nex*ample.com\n"))
+
+	scope, err := loadScope("")
+	assert.NoError(t, err)
+	assert.Nil(t, scope)
+
+	scope, err = loadScope(valid)
+	assert.NoError(t, err)
+	assert.True(t, scope.Contains("https://api.example.com/"))
+	assert.True(t, scope.Contains("http://10.1.2.3/"))
+	assert.False(t, scope.Contains("https://example.org/"))
+
+	_, err = loadScope(invalid)
+	assert.ErrorContains(t, err, "invalid.txt: scope line 2")
+
+	_, err = loadScope(filepath.Join(dir, "missing.txt"))
+	assert.Error(t, err)
+}
+
+func TestRejectedWriter(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "rejected.txt")
+	rejected, err := newRejectedWriter(name)
+	assert.NoError(t, err)
+
+	rejected.Write("https://other.com")
+	rejected.Write("https://admin.example.com")
+	rejected.Write("https://other.com")
+	assert.NoError(t, rejected.Close())
+
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://other.com\nhttps://admin.example.com\n", string(data))
+}
+
+func TestCloseAll(t *testing.T) {
+	var order []string
+	closers = []func() error{
+		func() error { order = append(order, "first"); return errors.New("first failed") },
+		func() error { order = append(order, "second"); return errors.New("second failed") },
 	}
-} 
\ No newline at end of file
+
+	assert.EqualError(t, closeAll(), "second failed")
+	assert.Equal(t, []string{"second", "first"}, order)
+	assert.Nil(t, closers)
+	assert.NoError(t, closeAll())
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/anatoliyv/cleanurl/pkg/cleanurl"
+)
+
+// Output formats accepted by --format
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatJSONL = "jsonl"
+	formatCSV   = "csv"
+	formatTSV   = "tsv"
+)
+
+// defaultColumns are the CSV/TSV columns written when --columns is not set.
+var defaultColumns = []string{"original", "url", "scheme", "host", "port", "path", "query", "fragment"}
+
+// columnValues extracts each column accepted by --columns from a result.
+var columnValues = map[string]func(cleanurl.Result) string{
+	"original": func(r cleanurl.Result) string { return r.Original },
+	"url":      func(r cleanurl.Result) string { return r.URL },
+	"scheme":   func(r cleanurl.Result) string { return r.Scheme },
+	"host":     func(r cleanurl.Result) string { return r.Host },
+	"port":     func(r cleanurl.Result) string { return r.Port },
+	"path":     func(r cleanurl.Result) string { return r.Path },
+	"query":    func(r cleanurl.Result) string { return r.RawQuery },
+	"fragment": func(r cleanurl.Result) string { return r.Fragment },
+	"rules":    func(r cleanurl.Result) string { return strings.Join(r.Rules, ",") },
+}
+
+// resultWriter writes cleaned URLs to w in one of the output formats, one
+// result at a time, so it can be used both for buffered and streamed output.
+type resultWriter struct {
+	w       io.Writer
+	format  string
+	count   int
+	csv     *csv.Writer
+	columns []string
+}
+
+// newResultWriter returns a writer for format. columns selects the CSV/TSV
+// columns; nil means defaultColumns.
+func newResultWriter(w io.Writer, format string, columns []string) (*resultWriter, error) {
+	rw := &resultWriter{w: w, format: format}
+
+	switch format {
+	case formatText, formatJSON, formatJSONL:
+		if columns != nil {
+			return nil, fmt.Errorf("--columns is only supported with csv and tsv formats")
+		}
+	case formatCSV, formatTSV:
+		if columns == nil {
+			columns = defaultColumns
+		}
+		for _, column := range columns {
+			if columnValues[column] == nil {
+				return nil, fmt.Errorf("invalid column %q", column)
+			}
+		}
+		rw.columns = columns
+		rw.csv = csv.NewWriter(w)
+		if format == formatTSV {
+			rw.csv.Comma = '\t'
+		}
+	default:
+		return nil, fmt.Errorf("invalid format %q (want text, json, jsonl, csv or tsv)", format)
+	}
+	return rw, nil
+}
+
+// Write writes a single result.
+func (rw *resultWriter) Write(r cleanurl.Result) error {
+	defer func() { rw.count++ }()
+
+	if rw.format == formatText {
+		_, err := fmt.Fprintln(rw.w, r.URL)
+		return err
+	}
+	if rw.csv != nil {
+		return rw.writeRecord(r)
+	}
+
+	// URLs are full of "&", which json.Marshal would escape for HTML
+	var record bytes.Buffer
+	encoder := json.NewEncoder(&record)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(r); err != nil {
+		return err
+	}
+
+	if rw.format == formatJSON {
+		separator := ",\n  "
+		if rw.count == 0 {
+			separator = "[\n  "
+		}
+		_, err := fmt.Fprintf(rw.w, "%s%s", separator, bytes.TrimSuffix(record.Bytes(), []byte("\n")))
+		return err
+	}
+	_, err := rw.w.Write(record.Bytes())
+	return err
+}
+
+// writeRecord writes r as a CSV/TSV row, preceded by the header row for the
+// first result. Rows are flushed immediately so that streamed output is not
+// held back.
+func (rw *resultWriter) writeRecord(r cleanurl.Result) error {
+	if rw.count == 0 {
+		if err := rw.csv.Write(rw.columns); err != nil {
+			return err
+		}
+	}
+
+	record := make([]string, 0, len(rw.columns))
+	for _, column := range rw.columns {
+		record = append(record, columnValues[column](r))
+	}
+	if err := rw.csv.Write(record); err != nil {
+		return err
+	}
+	rw.csv.Flush()
+	return rw.csv.Error()
+}
+
+// Close terminates the output, closing the JSON array if one was started.
+func (rw *resultWriter) Close() error {
+	if rw.csv != nil && rw.count == 0 {
+		// Still write the header so that consumers see the columns
+		if err := rw.csv.Write(rw.columns); err != nil {
+			return err
+		}
+		rw.csv.Flush()
+		return rw.csv.Error()
+	}
+	if rw.format != formatJSON {
+		return nil
+	}
+	if rw.count == 0 {
+		_, err := fmt.Fprintln(rw.w, "[]")
+		return err
+	}
+	_, err := fmt.Fprintln(rw.w, "\n]")
+	return err
+}
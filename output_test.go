@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/anatoliyv/cleanurl/pkg/cleanurl"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultWriter(t *testing.T) {
+	results := []cleanurl.Result{
+		{
+			Original:   "'https://example.com/?a=1&b=2'",
+			URL:        "https://example.com/?a=1&b=2",
+			Components: cleanurl.Components{Scheme: "https", Host: "example.com", Path: "/", Query: map[string][]string{"a": {"1"}, "b": {"2"}}, RawQuery: "a=1&b=2"},
+			Rules:      []string{"characters"},
+		},
+		{
+			URL:        "http://test.com",
+			Components: cleanurl.Components{Scheme: "http", Host: "test.com", Query: map[string][]string{}},
+			Rules:      []string{},
+		},
+	}
+	first := `{"original":"'https://example.com/?a=1&b=2'","url":"https://example.com/?a=1&b=2","scheme":"https","host":"example.com","port":"","path":"/","query":{"a":["1"],"b":["2"]},"fragment":"","rules":["characters"]}`
+	second := `{"original":"","url":"http://test.com","scheme":"http","host":"test.com","port":"","path":"","query":{},"fragment":"","rules":[]}`
+
+	tests := []struct {
+		name     string
+		format   string
+		columns  []string
+		results  []cleanurl.Result
+		expected string
+	}{
+		{
+			name:     "Text",
+			format:   formatText,
+			results:  results,
+			expected: "https://example.com/?a=1&b=2\nhttp://test.com\n",
+		},
+		{
+			name:     "JSON",
+			format:   formatJSON,
+			results:  results,
+			expected: "[\n  " + first + ",\n  " + second + "\n]\n",
+		},
+		{
+			name:     "JSON without results",
+			format:   formatJSON,
+			expected: "[]\n",
+		},
+		{
+			name:     "JSONL",
+			format:   formatJSONL,
+			results:  results,
+			expected: first + "\n" + second + "\n",
+		},
+		{
+			name:     "CSV with default columns",
+			format:   formatCSV,
+			results:  results,
+			expected: "original,url,scheme,host,port,path,query,fragment\n'https://example.com/?a=1&b=2',https://example.com/?a=1&b=2,https,example.com,,/,a=1&b=2,\n,http://test.com,http,test.com,,,,\n",
+		},
+		{
+			name:     "CSV quoting",
+			format:   formatCSV,
+			columns:  []string{"url", "rules"},
+			results:  []cleanurl.Result{{URL: `https://example.com/a,b?q="x"`, Rules: []string{"lower", "backslash"}}},
+			expected: "url,rules\n\"https://example.com/a,b?q=\"\"x\"\"\",\"lower,backslash\"\n",
+		},
+		{
+			name:     "TSV with selected columns",
+			format:   formatTSV,
+			columns:  []string{"host", "path"},
+			results:  []cleanurl.Result{{Components: cleanurl.Components{Host: "example.com", Path: "/a\tb"}}},
+			expected: "host\tpath\nexample.com\t\"/a\tb\"\n",
+		},
+		{
+			name:     "CSV without results",
+			format:   formatCSV,
+			columns:  []string{"url"},
+			expected: "url\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			rw, err := newResultWriter(&out, tt.format, tt.columns)
+			assert.NoError(t, err)
+
+			for _, r := range tt.results {
+				assert.NoError(t, rw.Write(r))
+			}
+			assert.NoError(t, rw.Close())
+			assert.Equal(t, tt.expected, out.String())
+		})
+	}
+}
+
+func TestNewResultWriterErrors(t *testing.T) {
+	_, err := newResultWriter(&bytes.Buffer{}, "xml", nil)
+	assert.Error(t, err)
+
+	_, err = newResultWriter(&bytes.Buffer{}, formatCSV, []string{"host", "bogus"})
+	assert.Error(t, err)
+
+	_, err = newResultWriter(&bytes.Buffer{}, formatJSON, []string{"host"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,118 @@
+package cleanurl
+
+import (
+	"hash/maphash"
+	"math"
+	"math/bits"
+)
+
+// Defaults used for a BloomFilter sized with invalid parameters.
+const (
+	DefaultExpectedCount = 10_000_000
+	DefaultFPRate        = 0.001
+)
+
+// BloomFilter is a set of strings of fixed size that may report a string it
+// has never seen as present, but never the reverse. Sized for n strings at a
+// false-positive rate p, it takes about -n*ln(p)/ln(2)² bits: 1.2 bytes per
+// string at 1%, 1.8 at 0.1%.
+type BloomFilter struct {
+	bits   []uint64
+	m      uint64 // number of bits
+	k      int    // number of hash functions
+	count  int
+	seed1  maphash.Seed
+	seed2  maphash.Seed
+	fpRate float64
+}
+
+// NewBloomFilter returns a filter sized for expected strings at a
+// false-positive rate of fpRate once they have all been added. An expected
+// count below 1 or a rate outside (0, 1) is replaced with its default.
+func NewBloomFilter(expected int, fpRate float64) *BloomFilter {
+	if expected < 1 {
+		expected = DefaultExpectedCount
+	}
+	if !(fpRate > 0 && fpRate < 1) {
+		fpRate = DefaultFPRate
+	}
+
+	m := uint64(math.Ceil(-float64(expected) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	m = (m + 63) / 64 * 64
+	k := int(math.Round(float64(m) / float64(expected) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+
+	return &BloomFilter{
+		bits:   make([]uint64, m/64),
+		m:      m,
+		k:      k,
+		seed1:  maphash.MakeSeed(),
+		seed2:  maphash.MakeSeed(),
+		fpRate: fpRate,
+	}
+}
+
+// Add adds key and reports whether it was new, that is not already (or
+// falsely reported as) present.
+func (b *BloomFilter) Add(key string) bool {
+	h1, h2 := b.hashes(key)
+	added := false
+	for i := 0; i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if b.bits[word]&mask == 0 {
+			b.bits[word] |= mask
+			added = true
+		}
+	}
+	if added {
+		b.count++
+	}
+	return added
+}
+
+// Contains reports whether key may have been added.
+func (b *BloomFilter) Contains(key string) bool {
+	h1, h2 := b.hashes(key)
+	for i := 0; i < b.k; i++ {
+		bit := (h1 + uint64(i)*h2) % b.m
+		if b.bits[bit/64]&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// hashes returns the two hashes of key that the k bit positions are derived
+// from by double hashing.
+func (b *BloomFilter) hashes(key string) (uint64, uint64) {
+	// A zero step would set a single bit k times
+	return maphash.String(b.seed1, key), maphash.String(b.seed2, key) | 1
+}
+
+// Count returns the number of keys added as new.
+func (b *BloomFilter) Count() int {
+	return b.count
+}
+
+// Size returns the size of the filter in bytes.
+func (b *BloomFilter) Size() int {
+	return len(b.bits) * 8
+}
+
+// FPRate returns the false-positive rate the filter was sized for.
+func (b *BloomFilter) FPRate() float64 {
+	return b.fpRate
+}
+
+// EstimatedFPRate returns the probability that a key never added is
+// reported present, estimated from the fraction of bits set.
+func (b *BloomFilter) EstimatedFPRate() float64 {
+	ones := 0
+	for _, word := range b.bits {
+		ones += bits.OnesCount64(word)
+	}
+	return math.Pow(float64(ones)/float64(b.m), float64(b.k))
+}
@@ -0,0 +1,123 @@
+package cleanurl
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBloomFilter(t *testing.T) {
+	b := NewBloomFilter(10000, 0.01)
+
+	for i := 0; i < 10000; i++ {
+		b.Add(fmt.Sprintf("https://example.com/%d", i))
+	}
+	// Added keys are never missed
+	for i := 0; i < 10000; i++ {
+		if !b.Contains(fmt.Sprintf("https://example.com/%d", i)) {
+			t.Fatalf("key %d is missing", i)
+		}
+	}
+	assert.False(t, b.Add("https://example.com/0"))
+
+	// Keys never added are reported at about the requested rate
+	falsePositives := 0
+	for i := 0; i < 100000; i++ {
+		if b.Contains(fmt.Sprintf("https://other.com/%d", i)) {
+			falsePositives++
+		}
+	}
+	rate := float64(falsePositives) / 100000
+	assert.InDelta(t, 0.01, rate, 0.005)
+	assert.InDelta(t, 0.01, b.EstimatedFPRate(), 0.005)
+
+	// About 9.6 bits per key at 1%
+	assert.InDelta(t, 12000, b.Size(), 200)
+	assert.InDelta(t, 10000, b.Count(), 100)
+}
+
+func TestNewBloomFilterDefaults(t *testing.T) {
+	b := NewBloomFilter(0, 2)
+	assert.Equal(t, DefaultFPRate, b.FPRate())
+	assert.Equal(t, NewBloomFilter(DefaultExpectedCount, DefaultFPRate).Size(), b.Size())
+
+	b = NewBloomFilter(1, 0.5)
+	assert.Equal(t, 0, b.Count())
+	assert.Equal(t, 0.0, b.EstimatedFPRate())
+	assert.True(t, b.Add("https://example.com"))
+	assert.True(t, b.Contains("https://example.com"))
+}
+
+func TestStreamApproxDedupe(t *testing.T) {
+	input := []string{
+		`"https://example.com/"`,
+		"http://example.com",
+		"http://unique.com",
+		"https://test.com",
+		"https://test.com/",
+		"http://unique.com/",
+		"https://example.com/page",
+	}
+
+	opts := DefaultOptions()
+	expected := runStream(New(opts).NewStream(), input)
+
+	// HTTP URLs are emitted in place instead of at the end
+	opts.ApproxDedupe = true
+	opts.ExpectedCount = 1000
+	opts.FPRate = 0.0001
+	assert.ElementsMatch(t, expected, runStream(New(opts).NewStream(), input))
+	assert.Equal(t, []string{"https://example.com", "http://unique.com", "https://test.com", "https://example.com/page"}, runStream(New(opts).NewStream(), input))
+
+	// An HTTP URL is only dropped if its HTTPS twin came first
+	s := New(opts).NewStream()
+	_, ok := s.Push("http://later.com")
+	assert.True(t, ok)
+	_, ok = s.Push("https://later.com")
+	assert.True(t, ok)
+	_, ok = s.Push("http://later.com/")
+	assert.False(t, ok)
+	assert.Empty(t, s.Flush())
+
+	s = New(opts).NewStream()
+	assert.Len(t, s.Filters(), 2)
+	s.Push("https://example.com")
+	assert.Equal(t, 1, s.Filters()[0].Count())
+	assert.Equal(t, 1, s.Filters()[1].Count())
+
+	opts.CleanHTTP = false
+	assert.Len(t, New(opts).NewStream().Filters(), 1)
+	opts.CleanHTTP = true
+
+	assert.Nil(t, New(DefaultOptions()).NewStream().Filters())
+
+	// Domain streams use the filter too
+	d := New(opts).NewDomainStream()
+	d.Push("https://a.example.com/x")
+	_, ok = d.Push("http://a.example.com/y")
+	assert.False(t, ok)
+	assert.Len(t, d.Filters(), 1)
+	assert.Equal(t, 1, d.Filters()[0].Count())
+}
+
+func TestStreamApproxDedupeFPRate(t *testing.T) {
+	opts := DefaultOptions()
+	opts.ApproxDedupe = true
+	opts.ExpectedCount = 10_000
+	opts.FPRate = 0.001
+
+	s := New(opts).NewStream()
+	dropped := 0
+	for i := 0; i < opts.ExpectedCount; i++ {
+		if _, ok := s.Push(fmt.Sprintf("https://example.com/page/%d", i)); !ok {
+			dropped++
+		}
+	}
+
+	// About 1.5 in 10k are dropped on average while the filters fill up
+	assert.Less(t, dropped, 30)
+	for _, filter := range s.Filters() {
+		assert.Less(t, filter.EstimatedFPRate(), 2*opts.FPRate)
+	}
+}
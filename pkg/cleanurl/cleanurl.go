@@ -0,0 +1,503 @@
+// Package cleanurl cleans and deduplicates URLs.
+//
+// It is the library behind the cleanurl command-line tool: a Cleaner built
+// from the same Options as the CLI flags produces exactly the same output.
+package cleanurl
+
+import (
+	"strings"
+)
+
+// Options controls which cleaning operations a Cleaner applies.
+type Options struct {
+	// Characters removes unnecessary characters (quotes and exclamation marks).
+	Characters bool
+	// CleanHTTP removes HTTP duplicates when an HTTPS version exists.
+	CleanHTTP bool
+	// Backslash removes trailing slashes to deduplicate URLs.
+	Backslash bool
+	// Lower selects which part of URLs is converted to lowercase.
+	Lower LowerMode
+	// Normalize parses URLs with net/url and applies the RFC 3986 section 6
+	// normalizations: lowercase scheme and host, uppercase percent-encoding,
+	// decode unreserved characters, remove dot segments, drop default ports.
+	Normalize bool
+	// DedupeKey compares URLs by a case-insensitive key (lowercased, quotes
+	// and trailing slash trimmed) after cleaning, but outputs the first
+	// spelling seen, cleaned by every other step but not lowercased.
+	DedupeKey bool
+	// RootDomains makes Domains collapse hosts to their registrable domain
+	// (eTLD+1), such as example.co.uk, using the embedded Public Suffix List.
+	RootDomains bool
+	// PrivateSuffixRoots ignores the private section of the Public Suffix
+	// List in RootDomains, so that suffixes such as github.io are reported as
+	// roots themselves instead of user.github.io.
+	PrivateSuffixRoots bool
+	// Under restricts Subdomains to hosts below this domain.
+	Under string
+	// StripTracking removes tracking query parameters such as utm_source and
+	// fbclid, so that URLs differing only in those deduplicate.
+	StripTracking bool
+	// TrackingRules are the parameters removed by StripTracking. Nil means
+	// DefaultTrackingRules.
+	TrackingRules *TrackingRules
+	// SortQuery orders query parameters by name, keeping the relative order
+	// of repeated names, so that ?b=2&a=1 and ?a=1&b=2 deduplicate.
+	SortQuery bool
+	// DropEmptyParams removes query parameters with an empty value (a= but
+	// not a flag such as debug) and empty ones left by "&&".
+	DropEmptyParams bool
+	// DropEmptyQuery removes a "?" that has no query after it.
+	DropEmptyQuery bool
+	// PercentSpaces writes spaces in queries as %20 instead of "+".
+	PercentSpaces bool
+	// DedupeParams keeps one URL per scheme, host, path and set of query
+	// parameter names, ignoring parameter values, so that /item?id=1 and
+	// /item?id=2 deduplicate.
+	DedupeParams bool
+	// CollapsePaths deduplicates URLs by their path pattern, in which numeric
+	// IDs, UUIDs, hex hashes, dates and slugs are replaced with placeholders
+	// such as /users/{int}/orders/{int}.
+	CollapsePaths bool
+	// Keep selects the URL output for each DedupeParams or CollapsePaths key.
+	// Streams always keep the first.
+	Keep Representative
+	// Patterns outputs the pattern that DedupeParams and CollapsePaths
+	// compare URLs by instead of a sample URL.
+	Patterns bool
+	// ExcludeExt drops URLs whose path has one of these file extensions, such
+	// as "png" or ".css". The query is ignored, so logo.png?v=3 is a png.
+	ExcludeExt []string
+	// IncludeExt keeps only URLs whose path has one of these extensions.
+	IncludeExt []string
+	// Drop excludes the extensions of the named categories: images, fonts,
+	// styles, media and docs. See CategoryExtensions.
+	Drop []string
+	// Match keeps only the cleaned URLs that match at least one matcher.
+	Match []*Matcher
+	// Exclude drops the cleaned URLs that match any matcher.
+	Exclude []*Matcher
+	// Scope keeps only the cleaned URLs whose host and path it contains.
+	Scope *Scope
+	// OutOfScope drops the cleaned URLs whose host and path it contains.
+	OutOfScope *Scope
+	// OnOutOfScope, if set, is called with every cleaned URL dropped by Scope
+	// or OutOfScope, duplicates included.
+	OnOutOfScope func(url string)
+	// Seen drops the URLs whose key is in the set, such as the URLs output by
+	// previous runs, and adds the key of every URL output. Keys are the ones
+	// URLs are deduplicated by, so DedupeKey, DedupeParams and CollapsePaths
+	// apply across runs too. The caller saves the set.
+	Seen *SeenSet
+	// ApproxDedupe makes Streams remember the keys they have seen in a
+	// BloomFilter of fixed size instead of a map, for endless inputs where
+	// memory must stay bounded and an occasional unique URL may be dropped
+	// as a duplicate. With CleanHTTP, HTTP URLs are emitted at once rather
+	// than held until the HTTPS twin is known.
+	ApproxDedupe bool
+	// ExpectedCount is the number of distinct URLs the ApproxDedupe filters
+	// are sized for. Zero means DefaultExpectedCount.
+	ExpectedCount int
+	// FPRate is the false-positive rate of each ApproxDedupe filter once
+	// ExpectedCount URLs are in it. Zero means DefaultFPRate.
+	FPRate float64
+}
+
+// DefaultOptions returns the options used by the CLI when no flags are given.
+func DefaultOptions() Options {
+	return Options{
+		Characters: true,
+		CleanHTTP:  true,
+		Backslash:  true,
+		Lower:      LowerHost,
+	}
+}
+
+// Cleaner applies a fixed set of cleaning operations to URLs.
+type Cleaner struct {
+	opts     Options
+	filter   *extensionFilter
+	spelling *Cleaner // cleans without lowercasing, with DedupeKey
+}
+
+// New returns a Cleaner configured with opts.
+func New(opts Options) *Cleaner {
+	c := &Cleaner{opts: opts, filter: newExtensionFilter(opts)}
+	if opts.DedupeKey {
+		spelling := opts
+		spelling.Lower, spelling.DedupeKey = LowerNone, false
+		c.spelling = New(spelling)
+	}
+	return c
+}
+
+// Options returns the options the Cleaner was created with.
+func (c *Cleaner) Options() Options {
+	return c.opts
+}
+
+// Clean cleans and deduplicates urls, preserving the order of first occurrence.
+// URLs dropped by the extension filters, the matchers or the scope are left
+// out.
+func (c *Cleaner) Clean(urls []string) []string {
+	results := c.clean(urls)
+	cleaned := make([]string, 0, len(results))
+	for _, r := range results {
+		cleaned = append(cleaned, r.URL)
+	}
+	return cleaned
+}
+
+// clean runs the cleaning pipeline over urls and returns one Result per
+// output URL, without parsed components.
+func (c *Cleaner) clean(urls []string) []Result {
+	results, _ := c.cleanKeys(urls)
+	return results
+}
+
+// cleanKeys is clean that also returns the key each output URL was
+// deduplicated by.
+func (c *Cleaner) cleanKeys(urls []string) ([]Result, []string) {
+	if len(urls) == 0 {
+		return []Result{}, []string{}
+	}
+	originals := urls
+	rules := make([][]string, len(urls))
+
+	// apply runs a cleaning step and records its rule for every URL it changed
+	apply := func(rule string, step func([]string) []string) {
+		cleaned := step(urls)
+		for i := range cleaned {
+			if cleaned[i] != urls[i] {
+				rules[i] = append(rules[i], rule)
+			}
+		}
+		urls = cleaned
+	}
+
+	// Step 1: Convert to lowercase
+	switch c.opts.Lower {
+	case LowerHost:
+		apply(RuleLower, convertHostToLowercase)
+	case LowerAll:
+		apply(RuleLower, convertToLowercase)
+	}
+
+	// Step 2: Remove unnecessary characters
+	if c.opts.Characters {
+		apply(RuleCharacters, removeUnnecessaryCharacters)
+	}
+
+	// Step 2b: Strip tracking parameters
+	if c.opts.StripTracking {
+		apply(RuleStripTracking, c.stripTracking)
+	}
+
+	// Step 2c: Rewrite queries into a canonical form
+	if c.rewritesQuery() {
+		apply(RuleQuery, c.canonicalQueries)
+	}
+
+	// Step 3: Apply RFC 3986 normalization
+	if c.opts.Normalize {
+		apply(RuleNormalize, normalizeURLs)
+	}
+
+	// Step 4: Create maps for tracking
+	index := make(map[string]int)
+	httpsMap := make(map[string]bool)
+	droppedHTTP := make(map[string]bool)
+	var result []Result
+	var keys []string
+
+	// First pass: drop filtered URLs and collect HTTPS URLs
+	kept := make([]bool, len(urls))
+	for i, url := range urls {
+		// Filters see the URL as it is output
+		if c.opts.Backslash {
+			url = trimTrailingSlash(url)
+		}
+		if kept[i] = c.keeps(url); !kept[i] {
+			continue
+		}
+		key := c.key(url)
+
+		// Track HTTPS URLs by their normalized form
+		if strings.HasPrefix(key, "https://") {
+			normalized := normalizeURLForComparison(key)
+			httpsMap[normalized] = true
+		}
+	}
+
+	// Second pass: process URLs
+	for i, url := range urls {
+		// Drop filtered file extensions and unmatched URLs
+		if !kept[i] {
+			continue
+		}
+		processedURL := url
+		shouldAdd := true
+
+		// Handle trailing slashes first
+		if c.opts.Backslash {
+			processedURL = strings.TrimSuffix(url, "/")
+			if processedURL != url {
+				rules[i] = append(rules[i], RuleBackslash)
+			}
+		}
+
+		// Compare by key but output the original spelling, or the pattern
+		key := c.key(processedURL)
+		switch {
+		case c.opts.Patterns:
+			processedURL = c.pattern(processedURL)
+		case c.opts.DedupeKey:
+			processedURL = c.originalSpelling(originals[i])
+		}
+
+		// Handle HTTP/HTTPS duplicates (check the processed URL)
+		if c.opts.CleanHTTP && strings.HasPrefix(key, "http://") {
+			normalized := normalizeURLForComparison(key)
+			if httpsMap[normalized] {
+				shouldAdd = false // Skip HTTP if HTTPS exists
+				droppedHTTP[normalized] = true
+			}
+		}
+
+		if !shouldAdd {
+			continue
+		}
+		r := Result{Original: originals[i], URL: processedURL, Rules: rules[i]}
+
+		// Add to result if not already processed, or replace the kept URL
+		if idx, seen := index[key]; !seen {
+			index[key] = len(result)
+			result = append(result, r)
+			keys = append(keys, key)
+		} else if (c.opts.DedupeParams || c.opts.CollapsePaths) && c.opts.Keep.replaces(result[idx].URL, processedURL) {
+			result[idx] = r
+		}
+	}
+
+	// Credit the HTTPS URLs that replaced an HTTP duplicate
+	for i, key := range keys {
+		if strings.HasPrefix(key, "https://") && droppedHTTP[normalizeURLForComparison(key)] {
+			result[i].Rules = append(result[i].Rules, RuleCleanHTTP)
+		}
+	}
+
+	// Drop the URLs output by previous runs
+	if c.opts.Seen != nil {
+		unseen, unseenKeys := result[:0], keys[:0]
+		for i, key := range keys {
+			if c.opts.Seen.Add(key) {
+				unseen = append(unseen, result[i])
+				unseenKeys = append(unseenKeys, key)
+			}
+		}
+		result, keys = unseen, unseenKeys
+	}
+
+	return result, keys
+}
+
+// CleanOne applies the per-URL cleaning operations to a single URL. It does
+// not deduplicate, so HTTP URLs are returned even when CleanHTTP is set, and
+// DedupeKey has no effect.
+func (c *Cleaner) CleanOne(url string) string {
+	cleaned, _ := c.cleanOne(url)
+	return cleaned
+}
+
+// cleanOne is CleanOne that also reports the rules that changed the URL.
+func (c *Cleaner) cleanOne(url string) (string, []string) {
+	var rules []string
+
+	// apply runs a cleaning step and records its rule if it changed the URL
+	apply := func(rule string, step func(string) string) {
+		if cleaned := step(url); cleaned != url {
+			rules = append(rules, rule)
+			url = cleaned
+		}
+	}
+
+	switch c.opts.Lower {
+	case LowerHost:
+		apply(RuleLower, lowercaseSchemeAndHost)
+	case LowerAll:
+		apply(RuleLower, strings.ToLower)
+	}
+	if c.opts.Characters {
+		apply(RuleCharacters, trimUnnecessaryCharacters)
+	}
+	if c.opts.StripTracking {
+		apply(RuleStripTracking, c.trackingRules().Strip)
+	}
+	if c.rewritesQuery() {
+		apply(RuleQuery, c.canonicalQuery)
+	}
+	if c.opts.Normalize {
+		apply(RuleNormalize, normalizeRFC3986)
+	}
+	if c.opts.Backslash {
+		apply(RuleBackslash, trimTrailingSlash)
+	}
+	return url, rules
+}
+
+// Domains returns the unique domain names found in urls, in order of first
+// occurrence. Domains are always lowercased and stripped of the www. prefix
+// and port. With Normalize set, the host is taken from the parsed URL so
+// userinfo is ignored. With RootDomains set, registrable domains are returned.
+// URLs dropped by the extension filters, the matchers or the scope are
+// skipped.
+func (c *Cleaner) Domains(urls []string) []string {
+	kept := make([]string, 0, len(urls))
+	for _, url := range urls {
+		if c.keepsInput(url) {
+			kept = append(kept, url)
+		}
+	}
+	return uniqueDomains(kept, c.domainExtractor())
+}
+
+// originalSpelling returns the form url is output in with DedupeKey: cleaned
+// by every step but lowercasing, so that only its case is kept.
+func (c *Cleaner) originalSpelling(url string) string {
+	spelled, _ := c.spelling.cleanOne(url)
+	return spelled
+}
+
+// key returns the string a cleaned URL is deduplicated by. Equivalent IPv6
+// literals always share a key, and so do URLs with the same pattern.
+func (c *Cleaner) key(url string) string {
+	if c.opts.DedupeKey {
+		url = dedupeKey(url)
+	}
+	return canonicalizeURLHost(c.pattern(url))
+}
+
+// domainExtractor returns the function used to pull the domain out of a URL.
+func (c *Cleaner) domainExtractor() func(string) string {
+	extract := c.hostExtractor()
+	if !c.opts.RootDomains {
+		return extract
+	}
+	return func(url string) string {
+		return rootDomain(extract(url), c.opts.PrivateSuffixRoots)
+	}
+}
+
+// hostExtractor returns the function used to pull the full host out of a URL.
+func (c *Cleaner) hostExtractor() func(string) string {
+	if c.opts.Normalize {
+		return extractParsedDomain
+	}
+	return extractDomain
+}
+
+func convertToLowercase(urls []string) []string {
+	if len(urls) == 0 {
+		return []string{}
+	}
+	var result []string
+	for _, url := range urls {
+		result = append(result, strings.ToLower(url))
+	}
+	return result
+}
+
+func removeUnnecessaryCharacters(urls []string) []string {
+	if len(urls) == 0 {
+		return []string{}
+	}
+	var result []string
+	for _, url := range urls {
+		result = append(result, trimUnnecessaryCharacters(url))
+	}
+	return result
+}
+
+func trimUnnecessaryCharacters(url string) string {
+	return strings.Trim(url, `"'!`)
+}
+
+func trimTrailingSlash(url string) string {
+	return strings.TrimSuffix(url, "/")
+}
+
+// normalizeURLForComparison removes protocol and trailing slash for HTTP/HTTPS comparison
+func normalizeURLForComparison(url string) string {
+	// Remove protocol
+	if strings.HasPrefix(url, "https://") {
+		url = strings.TrimPrefix(url, "https://")
+	} else if strings.HasPrefix(url, "http://") {
+		url = strings.TrimPrefix(url, "http://")
+	}
+
+	// Remove trailing slash
+	url = strings.TrimSuffix(url, "/")
+
+	// Canonicalize IPv6 literals
+	return canonicalizeURLHost(url)
+}
+
+// dedupeKey returns the case-insensitive key used to compare URLs in
+// DedupeKey mode: lowercased, with quotes and a trailing slash trimmed.
+func dedupeKey(url string) string {
+	url = strings.ToLower(url)
+	url = strings.Trim(url, `"'!`)
+	return strings.TrimSuffix(url, "/")
+}
+
+func extractUniqueDomains(urls []string) []string {
+	return uniqueDomains(urls, extractDomain)
+}
+
+func uniqueDomains(urls []string, extract func(string) string) []string {
+	if len(urls) == 0 {
+		return []string{}
+	}
+
+	domainMap := make(map[string]bool)
+	var result []string
+
+	for _, url := range urls {
+		// Convert to lowercase first
+		url = strings.ToLower(url)
+
+		// Remove unnecessary characters
+		url = strings.Trim(url, `"'!`)
+
+		// Extract domain
+		domain := extract(url)
+		if domain != "" && !domainMap[domain] {
+			domainMap[domain] = true
+			result = append(result, domain)
+		}
+	}
+
+	return result
+}
+
+func extractDomain(url string) string {
+	// Remove protocol
+	if strings.HasPrefix(url, "http://") {
+		url = strings.TrimPrefix(url, "http://")
+	} else if strings.HasPrefix(url, "https://") {
+		url = strings.TrimPrefix(url, "https://")
+	}
+
+	// Remove www. prefix if present
+	url = strings.TrimPrefix(url, "www.")
+
+	// Get the domain part (before the first slash or path)
+	if idx := strings.Index(url, "/"); idx != -1 {
+		url = url[:idx]
+	}
+
+	// Remove port if present, keeping IPv6 literals intact
+	host, _ := splitHostPort(url)
+
+	return comparisonHost(host)
+}
@@ -0,0 +1,585 @@
+package cleanurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertToLowercase(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		expected []string
+	}{
+		{
+			name:     "Convert mixed case URLs",
+			input:    []string{"HTTPS://EXAMPLE.COM", "Http://Test.Com", "https://lowercase.com"},
+			expected: []string{"https://example.com", "http://test.com", "https://lowercase.com"},
+		},
+		{
+			name:     "Convert uppercase URLs",
+			input:    []string{"HTTPS://EXAMPLE.COM", "HTTP://TEST.COM"},
+			expected: []string{"https://example.com", "http://test.com"},
+		},
+		{
+			name:     "Already lowercase URLs",
+			input:    []string{"https://example.com", "http://test.com"},
+			expected: []string{"https://example.com", "http://test.com"},
+		},
+		{
+			name:     "Empty input",
+			input:    []string{},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := convertToLowercase(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestRemoveUnnecessaryCharacters(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		expected []string
+	}{
+		{
+			name:     "Remove single quotes",
+			input:    []string{"'https://example.com'", "https://test.com"},
+			expected: []string{"https://example.com", "https://test.com"},
+		},
+		{
+			name:     "Remove double quotes",
+			input:    []string{`"https://example.com"`, "https://test.com"},
+			expected: []string{"https://example.com", "https://test.com"},
+		},
+		{
+			name:     "Remove mixed quotes",
+			input:    []string{`"https://example.com"`, "'https://test.com'"},
+			expected: []string{"https://example.com", "https://test.com"},
+		},
+		{
+			name:     "Remove exclamation marks",
+			input:    []string{"!https://example.com!", "https://test.com"},
+			expected: []string{"https://example.com", "https://test.com"},
+		},
+		{
+			name:     "Remove mixed characters",
+			input:    []string{`"https://example.com"`, "'https://test.com'", "!https://another.com!"},
+			expected: []string{"https://example.com", "https://test.com", "https://another.com"},
+		},
+		{
+			name:     "No quotes to remove",
+			input:    []string{"https://example.com", "https://test.com"},
+			expected: []string{"https://example.com", "https://test.com"},
+		},
+		{
+			name:     "Empty input",
+			input:    []string{},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := removeUnnecessaryCharacters(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestClean(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      []string
+		characters bool
+		cleanHTTP  bool
+		backslash  bool
+		lower      LowerMode
+		expected   []string
+	}{
+		{
+			name:       "All features enabled",
+			input:      []string{`"https://example.com/"`, "http://example.com", "https://example.com"},
+			characters: true,
+			cleanHTTP:  true,
+			backslash:  true,
+			lower:      LowerAll,
+			expected:   []string{"https://example.com"},
+		},
+		{
+			name:       "Characters disabled",
+			input:      []string{`"https://example.com"`, "https://test.com"},
+			characters: false,
+			cleanHTTP:  true,
+			backslash:  true,
+			lower:      LowerAll,
+			expected:   []string{`"https://example.com"`, "https://test.com"},
+		},
+		{
+			name:       "HTTP cleaning disabled",
+			input:      []string{"http://example.com", "https://example.com"},
+			characters: true,
+			cleanHTTP:  false,
+			backslash:  true,
+			lower:      LowerAll,
+			expected:   []string{"http://example.com", "https://example.com"},
+		},
+		{
+			name:       "Backslash cleaning disabled",
+			input:      []string{"https://example.com/", "https://example.com"},
+			characters: true,
+			cleanHTTP:  true,
+			backslash:  false,
+			lower:      LowerAll,
+			expected:   []string{"https://example.com/", "https://example.com"},
+		},
+		{
+			name:       "Empty input",
+			input:      []string{},
+			characters: true,
+			cleanHTTP:  true,
+			backslash:  true,
+			lower:      LowerAll,
+			expected:   []string{},
+		},
+		{
+			name:       "Complex deduplication",
+			input:      []string{`"http://example.com/"`, "https://example.com", "'https://test.com/'", "https://test.com"},
+			characters: true,
+			cleanHTTP:  true,
+			backslash:  true,
+			lower:      LowerAll,
+			expected:   []string{"https://example.com", "https://test.com"},
+		},
+		{
+			name:       "Lowercase conversion with mixed case",
+			input:      []string{"HTTPS://EXAMPLE.COM", "Http://Test.Com", "!https://UPPERCASE.com!"},
+			characters: true,
+			cleanHTTP:  true,
+			backslash:  true,
+			lower:      LowerAll,
+			expected:   []string{"https://example.com", "http://test.com", "https://uppercase.com"},
+		},
+		{
+			name:       "Host lowercasing preserves path and query case",
+			input:      []string{"HTTPS://Example.COM/Download/File.PDF?id=AbC", "http://example.com/Download/File.PDF?id=AbC", "https://example.com/download/file.pdf?id=abc"},
+			characters: true,
+			cleanHTTP:  true,
+			backslash:  true,
+			lower:      LowerHost,
+			expected:   []string{"https://example.com/Download/File.PDF?id=AbC", "https://example.com/download/file.pdf?id=abc"},
+		},
+		{
+			name:       "Host lowercasing with special characters",
+			input:      []string{"'HTTP://Example.com/Path/'", "!https://EXAMPLE.com/Path!"},
+			characters: true,
+			cleanHTTP:  true,
+			backslash:  true,
+			lower:      LowerHost,
+			expected:   []string{"https://example.com/Path"},
+		},
+		{
+			name:       "URLs with ports - HTTP/HTTPS deduplication",
+			input:      []string{"https://example.com:8080/path", "http://example.com:8080/path"},
+			characters: true,
+			cleanHTTP:  true,
+			backslash:  true,
+			lower:      LowerAll,
+			expected:   []string{"https://example.com:8080/path"},
+		},
+		{
+			name:       "URLs with different ports - no deduplication",
+			input:      []string{"https://example.com:8080/path", "http://example.com:9090/path"},
+			characters: true,
+			cleanHTTP:  true,
+			backslash:  true,
+			lower:      LowerAll,
+			expected:   []string{"https://example.com:8080/path", "http://example.com:9090/path"},
+		},
+		{
+			name:       "URLs with ports and trailing slashes",
+			input:      []string{"https://example.com:8080/path/", "http://example.com:8080/path/"},
+			characters: true,
+			cleanHTTP:  true,
+			backslash:  true,
+			lower:      LowerAll,
+			expected:   []string{"https://example.com:8080/path"},
+		},
+		{
+			name:       "Equivalent IPv6 literals deduplicate",
+			input:      []string{"https://[2001:db8::1]:8080/path", "http://[2001:DB8:0:0::1]:8080/path", "https://[2001:0db8::0001]:8080/path/"},
+			characters: true,
+			cleanHTTP:  true,
+			backslash:  true,
+			lower:      LowerHost,
+			expected:   []string{"https://[2001:db8::1]:8080/path"},
+		},
+		{
+			name:       "Complex port scenarios with special characters",
+			input:      []string{"'https://EXAMPLE.com:8080/path/'", `"http://example.com:9090/another"`, "https://www.EXAMPLE.com:443/different"},
+			characters: true,
+			cleanHTTP:  true,
+			backslash:  true,
+			lower:      LowerAll,
+			expected:   []string{"https://example.com:8080/path", "http://example.com:9090/another", "https://www.example.com:443/different"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleaner := New(Options{
+				Characters: tt.characters,
+				CleanHTTP:  tt.cleanHTTP,
+				Backslash:  tt.backslash,
+				Lower:      tt.lower,
+			})
+
+			result := cleaner.Clean(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestExtractDomain(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Basic HTTPS URL",
+			input:    "https://example.com/path",
+			expected: "example.com",
+		},
+		{
+			name:     "Basic HTTP URL",
+			input:    "http://example.com/path",
+			expected: "example.com",
+		},
+		{
+			name:     "URL with port",
+			input:    "https://example.com:8080/path",
+			expected: "example.com",
+		},
+		{
+			name:     "URL with different port",
+			input:    "http://example.com:9090/path",
+			expected: "example.com",
+		},
+		{
+			name:     "URL with www prefix",
+			input:    "https://www.example.com/path",
+			expected: "example.com",
+		},
+		{
+			name:     "URL with www prefix and port",
+			input:    "https://www.example.com:8080/path",
+			expected: "example.com",
+		},
+		{
+			name:     "URL with trailing slash",
+			input:    "https://example.com/path/",
+			expected: "example.com",
+		},
+		{
+			name:     "URL with port and trailing slash",
+			input:    "https://example.com:8080/path/",
+			expected: "example.com",
+		},
+		{
+			name:     "Domain only with port",
+			input:    "https://example.com:8080",
+			expected: "example.com",
+		},
+		{
+			name:     "Domain only without port",
+			input:    "https://example.com",
+			expected: "example.com",
+		},
+		{
+			name:     "Complex path with port",
+			input:    "https://example.com:8080/path/to/resource?param=value",
+			expected: "example.com",
+		},
+		{
+			name:     "IP address with port",
+			input:    "https://192.168.1.1:8080/path",
+			expected: "192.168.1.1",
+		},
+		{
+			name:     "IPv6 literal with port",
+			input:    "http://[2001:db8::1]:8080/x",
+			expected: "[2001:db8::1]",
+		},
+		{
+			name:     "IPv6 literal without port",
+			input:    "http://[::1]/",
+			expected: "[::1]",
+		},
+		{
+			name:     "IPv6 literal with zone",
+			input:    "http://[fe80::1%25en0]:8080/",
+			expected: "[fe80::1%25en0]",
+		},
+		{
+			name:     "IPv4-mapped IPv6 literal",
+			input:    "https://[::ffff:192.168.1.1]/path",
+			expected: "192.168.1.1",
+		},
+		{
+			name:     "Empty input",
+			input:    "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := extractDomain(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestNormalizeURLForComparison(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "HTTPS URL",
+			input:    "https://example.com/path",
+			expected: "example.com/path",
+		},
+		{
+			name:     "HTTP URL",
+			input:    "http://example.com/path",
+			expected: "example.com/path",
+		},
+		{
+			name:     "HTTPS URL with port",
+			input:    "https://example.com:8080/path",
+			expected: "example.com:8080/path",
+		},
+		{
+			name:     "HTTP URL with port",
+			input:    "http://example.com:8080/path",
+			expected: "example.com:8080/path",
+		},
+		{
+			name:     "URL with trailing slash",
+			input:    "https://example.com/path/",
+			expected: "example.com/path",
+		},
+		{
+			name:     "URL with port and trailing slash",
+			input:    "https://example.com:8080/path/",
+			expected: "example.com:8080/path",
+		},
+		{
+			name:     "Domain only with trailing slash",
+			input:    "https://example.com/",
+			expected: "example.com",
+		},
+		{
+			name:     "Domain with port and trailing slash",
+			input:    "https://example.com:8080/",
+			expected: "example.com:8080",
+		},
+		{
+			name:     "IPv6 literal in compressed form",
+			input:    "https://[2001:0db8:0:0:0:0:0:1]:8080/path/",
+			expected: "[2001:db8::1]:8080/path",
+		},
+		{
+			name:     "Empty input",
+			input:    "",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := normalizeURLForComparison(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestExtractUniqueDomains(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		expected []string
+	}{
+		{
+			name:     "Basic domains",
+			input:    []string{"https://example.com/path", "https://test.com/another"},
+			expected: []string{"example.com", "test.com"},
+		},
+		{
+			name:     "Domains with ports",
+			input:    []string{"https://example.com:8080/path", "http://test.com:9090/another"},
+			expected: []string{"example.com", "test.com"},
+		},
+		{
+			name:     "Same domain with different ports",
+			input:    []string{"https://example.com:8080/path", "http://example.com:9090/another"},
+			expected: []string{"example.com"},
+		},
+		{
+			name:     "Mixed case domains",
+			input:    []string{"https://EXAMPLE.com:8080/path", "http://example.com:9090/another"},
+			expected: []string{"example.com"},
+		},
+		{
+			name:     "Domains with special characters",
+			input:    []string{"'https://example.com:8080/path'", `"http://test.com:9090/another"`},
+			expected: []string{"example.com", "test.com"},
+		},
+		{
+			name:     "IPv6 domains",
+			input:    []string{"http://[::1]/", "https://[0:0::1]:8443/x", "http://[::ffff:10.0.0.1]/", "http://10.0.0.1:8080/"},
+			expected: []string{"[::1]", "10.0.0.1"},
+		},
+		{
+			name:     "Domains with www prefix",
+			input:    []string{"https://www.example.com:8080/path", "http://example.com:9090/another"},
+			expected: []string{"example.com"},
+		},
+		{
+			name: "Complex deduplication with ports",
+			input: []string{
+				"https://example.com:8080/path",
+				"http://example.com:8080/path",
+				"https://www.example.com:9090/another",
+				"http://test.com:8080/different",
+			},
+			expected: []string{"example.com", "test.com"},
+		},
+		{
+			name:     "Empty input",
+			input:    []string{},
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := extractUniqueDomains(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestCleanOne(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		input    string
+		expected string
+	}{
+		{
+			name:     "Default options",
+			opts:     DefaultOptions(),
+			input:    `"HTTPS://Example.com/Path/"`,
+			expected: "https://example.com/Path",
+		},
+		{
+			name:     "HTTP URL is kept",
+			opts:     DefaultOptions(),
+			input:    "http://example.com/",
+			expected: "http://example.com",
+		},
+		{
+			name:     "Lowercase everything",
+			opts:     Options{Lower: LowerAll},
+			input:    "HTTPS://Example.com/Path",
+			expected: "https://example.com/path",
+		},
+		{
+			name:     "All features disabled",
+			opts:     Options{},
+			input:    `"HTTPS://Example.com/"`,
+			expected: `"HTTPS://Example.com/"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := New(tt.opts).CleanOne(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestDomains(t *testing.T) {
+	cleaner := New(Options{})
+	result := cleaner.Domains([]string{"HTTPS://www.Example.com:8080/path", "http://example.com"})
+	assert.Equal(t, []string{"example.com"}, result)
+}
+
+func TestCleanWithDedupeKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		opts     Options
+		expected []string
+	}{
+		{
+			name:     "First original spelling is kept",
+			input:    []string{"https://Example.com/Download/File.PDF", "https://example.com/download/file.pdf/", `"HTTPS://EXAMPLE.COM/DOWNLOAD/FILE.PDF"`},
+			opts:     Options{DedupeKey: true},
+			expected: []string{"https://Example.com/Download/File.PDF"},
+		},
+		{
+			name:     "Cleaning options other than lowercasing apply to the output",
+			input:    []string{`"https://Example.com/Path/"`, "https://example.com/path"},
+			opts:     Options{Characters: true, Backslash: true, Lower: LowerAll, DedupeKey: true},
+			expected: []string{"https://Example.com/Path"},
+		},
+		{
+			name:     "Tracking parameters are stripped from the output",
+			input:    []string{"https://Example.com/Post?utm_source=x&id=1", "https://example.com/post?id=1"},
+			opts:     Options{StripTracking: true, DedupeKey: true},
+			expected: []string{"https://Example.com/Post?id=1"},
+		},
+		{
+			name:     "Normalization applies to the output",
+			input:    []string{"https://example.com/a/../B", "https://example.com/b"},
+			opts:     Options{Normalize: true, DedupeKey: true},
+			expected: []string{"https://example.com/B"},
+		},
+		{
+			name:     "HTTP is dropped when an HTTPS variant exists",
+			input:    []string{"HTTP://Example.com/A", "https://example.com/a/"},
+			opts:     Options{CleanHTTP: true, DedupeKey: true},
+			expected: []string{"https://example.com/a/"},
+		},
+		{
+			name:     "Distinct URLs are all kept",
+			input:    []string{"https://Example.com/A", "https://example.com/B"},
+			opts:     Options{DedupeKey: true},
+			expected: []string{"https://Example.com/A", "https://example.com/B"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := New(tt.opts).Clean(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestDedupeKey(t *testing.T) {
+	assert.Equal(t, "https://example.com/path", dedupeKey(`"HTTPS://Example.com/Path/"`))
+	assert.Equal(t, "", dedupeKey(""))
+}
@@ -0,0 +1,468 @@
+package cleanurl
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// External cleans and deduplicates inputs larger than memory, with the same
+// output as Clean.
+//
+// Pushed URLs are cleaned and filtered one at a time and buffered with their
+// dedupe key and input position. Whenever the buffer reaches the memory
+// limit, it is sorted by key and written to a temporary run file. Merging the
+// runs brings the URLs of each key, and of each HTTP and HTTPS twin,
+// together: the first of each key is kept (or the Keep representative) and
+// HTTP URLs are dropped when an HTTPS twin exists. The survivors are sorted
+// back into input order the same way, so the output is in order of first
+// occurrence.
+type External struct {
+	cleaner *Cleaner
+	dir     string
+	keys    *runSorter
+	seq     uint64
+}
+
+// spilled is a URL in a run: its Result, dedupe key and input position, and
+// the group of keys it is deduplicated with.
+type spilled struct {
+	group    string
+	key      string
+	seq      uint64
+	original string
+	url      string
+	rules    []string
+}
+
+// spilledOverhead approximates the memory a buffered spilled takes besides
+// its strings.
+const spilledOverhead = 128
+
+// mergeFanIn is the number of runs merged at once. More runs are merged in
+// several passes, which bounds open files and read buffers.
+const mergeFanIn = 64
+
+// NewExternal returns an External that keeps at most about memoryLimit bytes
+// of URLs in memory and spills the rest to temporary files in dir, or in the
+// default temporary directory if dir is empty. The files are removed by
+// Close.
+func (c *Cleaner) NewExternal(dir string, memoryLimit int64) (*External, error) {
+	tmp, err := os.MkdirTemp(dir, "cleanurl-")
+	if err != nil {
+		return nil, err
+	}
+	e := &External{cleaner: c, dir: tmp}
+	e.keys = newRunSorter(tmp, memoryLimit, lessByKey)
+	return e, nil
+}
+
+// Push cleans a single URL and adds it to the input. URLs dropped by the
+// filters are left out right away.
+func (e *External) Push(url string) error {
+	c := e.cleaner
+	processedURL, rules := c.cleanOne(url)
+	if !c.keeps(processedURL) {
+		return nil
+	}
+
+	// Compare by key but output the original spelling, or the pattern
+	key := c.key(processedURL)
+	switch {
+	case c.opts.Patterns:
+		processedURL = c.pattern(processedURL)
+	case c.opts.DedupeKey:
+		processedURL = c.originalSpelling(url)
+	}
+
+	e.seq++
+	return e.keys.add(spilled{
+		group:    e.group(key),
+		key:      key,
+		seq:      e.seq,
+		original: url,
+		url:      processedURL,
+		rules:    rules,
+	})
+}
+
+// group returns the key URLs are grouped by to find HTTP and HTTPS twins:
+// HTTP and HTTPS keys share a group named after the HTTPS one, and other keys
+// are alone in their group.
+func (e *External) group(key string) string {
+	if e.cleaner.opts.CleanHTTP && (strings.HasPrefix(key, "http://") || strings.HasPrefix(key, "https://")) {
+		return "https://" + normalizeURLForComparison(key)
+	}
+	return key
+}
+
+// Each calls fn with the cleaned URLs in order of first occurrence. It must be
+// called once, after the last Push.
+func (e *External) Each(fn func(url string) error) error {
+	return e.each(func(r Result) error {
+		return fn(r.URL)
+	})
+}
+
+// EachResult is Each with Results like CleanResults.
+func (e *External) EachResult(fn func(Result) error) error {
+	return e.each(func(r Result) error {
+		r.complete()
+		return fn(r)
+	})
+}
+
+func (e *External) each(fn func(Result) error) error {
+	ordered := newRunSorter(e.dir, e.keys.limit, lessBySeq)
+
+	// Keep one URL per key and drop HTTP twins, group by group
+	var reps []spilled
+	flush := func() error {
+		err := e.dedupeGroup(reps, ordered.add)
+		reps = reps[:0]
+		return err
+	}
+	err := e.keys.each(func(s spilled) error {
+		if len(reps) > 0 && reps[0].group != s.group {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+		if len(reps) > 0 && reps[len(reps)-1].key == s.key {
+			rep := &reps[len(reps)-1]
+			if (e.cleaner.opts.DedupeParams || e.cleaner.opts.CollapsePaths) && e.cleaner.opts.Keep.replaces(rep.url, s.url) {
+				// The representative keeps the position of the first URL
+				s.seq = rep.seq
+				*rep = s
+			}
+			return nil
+		}
+		reps = append(reps, s)
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return err
+	}
+
+	seen := e.cleaner.opts.Seen
+	return ordered.each(func(s spilled) error {
+		if seen != nil && !seen.Add(s.key) {
+			return nil
+		}
+		return fn(Result{Original: s.original, URL: s.url, Rules: s.rules})
+	})
+}
+
+// dedupeGroup passes on the URLs of a group, one per key, except HTTP URLs
+// when the group has an HTTPS key. The HTTPS URLs that replace them are
+// credited with RuleCleanHTTP.
+func (e *External) dedupeGroup(reps []spilled, add func(spilled) error) error {
+	hasHTTPS, droppedHTTP := false, false
+	for _, r := range reps {
+		hasHTTPS = hasHTTPS || strings.HasPrefix(r.key, "https://")
+	}
+
+	for _, r := range reps {
+		if e.cleaner.opts.CleanHTTP && hasHTTPS && strings.HasPrefix(r.key, "http://") {
+			droppedHTTP = true
+		}
+	}
+	for _, r := range reps {
+		switch {
+		case droppedHTTP && strings.HasPrefix(r.key, "http://"):
+			continue
+		case droppedHTTP && strings.HasPrefix(r.key, "https://"):
+			r.rules = append(r.rules[:len(r.rules):len(r.rules)], RuleCleanHTTP)
+		}
+		if err := add(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close removes the temporary files.
+func (e *External) Close() error {
+	e.keys.close()
+	return os.RemoveAll(e.dir)
+}
+
+// lessByKey orders spilled URLs by group, then HTTPS keys first so that a
+// group is known to have one before its HTTP keys, then by key and position.
+func lessByKey(a, b *spilled) bool {
+	if a.group != b.group {
+		return a.group < b.group
+	}
+	if httpsA, httpsB := strings.HasPrefix(a.key, "https://"), strings.HasPrefix(b.key, "https://"); httpsA != httpsB {
+		return httpsA
+	}
+	if a.key != b.key {
+		return a.key < b.key
+	}
+	return a.seq < b.seq
+}
+
+// lessBySeq orders spilled URLs by input position.
+func lessBySeq(a, b *spilled) bool {
+	return a.seq < b.seq
+}
+
+// runSorter sorts spilled URLs with a bounded amount of memory, writing
+// sorted runs to files and merging them.
+type runSorter struct {
+	dir   string
+	limit int64
+	less  func(a, b *spilled) bool
+	buf   []spilled
+	size  int64
+	runs  []string
+}
+
+func newRunSorter(dir string, limit int64, less func(a, b *spilled) bool) *runSorter {
+	return &runSorter{dir: dir, limit: limit, less: less}
+}
+
+// add buffers s, spilling the buffer to a run when it is full.
+func (rs *runSorter) add(s spilled) error {
+	rs.buf = append(rs.buf, s)
+	rs.size += spilledSize(&s)
+	if rs.size < rs.limit {
+		return nil
+	}
+	return rs.spill()
+}
+
+// spill writes the sorted buffer to a new run.
+func (rs *runSorter) spill() error {
+	sort.Slice(rs.buf, func(i, j int) bool { return rs.less(&rs.buf[i], &rs.buf[j]) })
+	name, err := rs.writeRun(func(write func(*spilled) error) error {
+		for i := range rs.buf {
+			if err := write(&rs.buf[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	rs.runs = append(rs.runs, name)
+	rs.buf, rs.size = nil, 0
+	return nil
+}
+
+// each calls fn with every URL added, in order. Without runs, the buffer is
+// sorted in memory.
+func (rs *runSorter) each(fn func(spilled) error) error {
+	if len(rs.runs) == 0 {
+		sort.Slice(rs.buf, func(i, j int) bool { return rs.less(&rs.buf[i], &rs.buf[j]) })
+		for _, s := range rs.buf {
+			if err := fn(s); err != nil {
+				return err
+			}
+		}
+		rs.buf = nil
+		return nil
+	}
+
+	if len(rs.buf) > 0 {
+		if err := rs.spill(); err != nil {
+			return err
+		}
+	}
+
+	// Merge in passes until the remaining runs can be merged at once
+	for len(rs.runs) > mergeFanIn {
+		batch := rs.runs[:mergeFanIn]
+		name, err := rs.writeRun(func(write func(*spilled) error) error {
+			return rs.merge(batch, func(s spilled) error { return write(&s) })
+		})
+		if err != nil {
+			return err
+		}
+		rs.runs = append(rs.runs[mergeFanIn:], name)
+	}
+
+	err := rs.merge(rs.runs, fn)
+	rs.close()
+	return err
+}
+
+// writeRun creates a run file and fills it with the records write is called
+// with.
+func (rs *runSorter) writeRun(fill func(write func(*spilled) error) error) (string, error) {
+	f, err := os.CreateTemp(rs.dir, "run-")
+	if err != nil {
+		return "", err
+	}
+	w := bufio.NewWriter(f)
+	err = fill(func(s *spilled) error {
+		return writeSpilled(w, s)
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// merge calls fn with the records of the runs in order and removes the runs.
+func (rs *runSorter) merge(runs []string, fn func(spilled) error) error {
+	h := &runHeap{less: rs.less}
+	defer func() {
+		for _, r := range h.readers {
+			r.f.Close()
+		}
+		for _, name := range runs {
+			os.Remove(name)
+		}
+	}()
+
+	for _, name := range runs {
+		f, err := os.Open(name)
+		if err != nil {
+			return err
+		}
+		r := &runReader{f: f, r: bufio.NewReader(f)}
+		if err := r.next(); err == io.EOF {
+			f.Close()
+			continue
+		} else if err != nil {
+			f.Close()
+			return err
+		}
+		h.readers = append(h.readers, r)
+	}
+	heap.Init(h)
+
+	for h.Len() > 0 {
+		r := h.readers[0]
+		if err := fn(r.cur); err != nil {
+			return err
+		}
+		switch err := r.next(); {
+		case err == io.EOF:
+			heap.Pop(h)
+			r.f.Close()
+		case err != nil:
+			return err
+		default:
+			heap.Fix(h, 0)
+		}
+	}
+	return nil
+}
+
+// close removes the remaining runs.
+func (rs *runSorter) close() {
+	for _, name := range rs.runs {
+		os.Remove(name)
+	}
+	rs.runs = nil
+}
+
+// spilledSize approximates the memory taken by s.
+func spilledSize(s *spilled) int64 {
+	size := spilledOverhead + len(s.group) + len(s.key) + len(s.original) + len(s.url)
+	for _, rule := range s.rules {
+		size += len(rule) + 16
+	}
+	return int64(size)
+}
+
+// runReader reads the records of a run one at a time.
+type runReader struct {
+	f   *os.File
+	r   *bufio.Reader
+	cur spilled
+}
+
+func (r *runReader) next() error {
+	return readSpilled(r.r, &r.cur)
+}
+
+// runHeap orders run readers by their current record.
+type runHeap struct {
+	readers []*runReader
+	less    func(a, b *spilled) bool
+}
+
+func (h *runHeap) Len() int           { return len(h.readers) }
+func (h *runHeap) Less(i, j int) bool { return h.less(&h.readers[i].cur, &h.readers[j].cur) }
+func (h *runHeap) Swap(i, j int)      { h.readers[i], h.readers[j] = h.readers[j], h.readers[i] }
+func (h *runHeap) Push(x any)         { h.readers = append(h.readers, x.(*runReader)) }
+
+func (h *runHeap) Pop() any {
+	last := h.readers[len(h.readers)-1]
+	h.readers = h.readers[:len(h.readers)-1]
+	return last
+}
+
+// writeSpilled encodes s as its position followed by length-prefixed
+// strings.
+func writeSpilled(w *bufio.Writer, s *spilled) error {
+	var buf [binary.MaxVarintLen64]byte
+	w.Write(buf[:binary.PutUvarint(buf[:], s.seq)])
+	w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(s.rules)))])
+	for _, str := range append([]string{s.group, s.key, s.original, s.url}, s.rules...) {
+		w.Write(buf[:binary.PutUvarint(buf[:], uint64(len(str)))])
+		if _, err := w.WriteString(str); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readSpilled decodes the next record into s, returning io.EOF at the end of
+// the run.
+func readSpilled(r *bufio.Reader, s *spilled) error {
+	seq, err := binary.ReadUvarint(r)
+	if err != nil {
+		return err
+	}
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return unexpectedEOF(err)
+	}
+
+	fields := make([]string, 4+count)
+	for i := range fields {
+		length, err := binary.ReadUvarint(r)
+		if err != nil {
+			return unexpectedEOF(err)
+		}
+		buf := make([]byte, length)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return unexpectedEOF(err)
+		}
+		fields[i] = string(buf)
+	}
+
+	*s = spilled{group: fields[0], key: fields[1], seq: seq, original: fields[2], url: fields[3]}
+	if count > 0 {
+		s.rules = fields[4:]
+	}
+	return nil
+}
+
+// unexpectedEOF reports a run that ends in the middle of a record.
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
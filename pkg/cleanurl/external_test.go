@@ -0,0 +1,126 @@
+package cleanurl
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// externalInput has duplicates, HTTP and HTTPS twins on either side,
+// trailing slashes and case differences spread far apart.
+func externalInput() []string {
+	var urls []string
+	for i := 0; i < 300; i++ {
+		n := i * 7 % 50
+		switch i % 7 {
+		case 0:
+			urls = append(urls, fmt.Sprintf("http://Host%d.example.com/item/%d?id=%d", n%13, n, i))
+		case 1:
+			urls = append(urls, fmt.Sprintf("https://host%d.example.com/item/%d/?id=%d", n%17, n, i%4))
+		case 2:
+			urls = append(urls, fmt.Sprintf(`"https://host%d.example.com/item/%d?id=%d"`, n%13, n, i%3))
+		case 3:
+			urls = append(urls, fmt.Sprintf("http://host%d.example.com/page-%d.png", n%5, n%9))
+		case 4:
+			urls = append(urls, fmt.Sprintf("ftp://files.example.com/%d", n%11))
+		case 5:
+			urls = append(urls, fmt.Sprintf("HTTPS://Host%d.example.com/Item/%d", n%7, n%20))
+		default:
+			urls = append(urls, fmt.Sprintf("http://host%d.example.com/Item/%d/", n%7, n%20))
+		}
+	}
+	return urls
+}
+
+func TestExternalMatchesClean(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Options)
+	}{
+		{name: "Default options"},
+		{name: "Without CleanHTTP", modify: func(o *Options) { o.CleanHTTP = false }},
+		{name: "Without Backslash", modify: func(o *Options) { o.Backslash = false }},
+		{name: "DedupeKey", modify: func(o *Options) { o.DedupeKey = true }},
+		{name: "DedupeParams keeping the last", modify: func(o *Options) { o.DedupeParams = true; o.Keep = KeepLast }},
+		{name: "CollapsePaths keeping the longest", modify: func(o *Options) { o.CollapsePaths = true; o.Keep = KeepLongest }},
+		{name: "Patterns", modify: func(o *Options) { o.CollapsePaths = true; o.Patterns = true }},
+		{name: "Filters", modify: func(o *Options) { o.Drop = []string{"images"} }},
+	}
+
+	input := externalInput()
+	for _, tt := range tests {
+		opts := DefaultOptions()
+		if tt.modify != nil {
+			tt.modify(&opts)
+		}
+		cleaner := New(opts)
+		expected := cleaner.CleanResults(input)
+
+		// A tiny limit spills every few URLs and needs several merge passes
+		for _, limit := range []int64{1, 1024, 1 << 30} {
+			t.Run(fmt.Sprintf("%s/limit %d", tt.name, limit), func(t *testing.T) {
+				e, err := cleaner.NewExternal(t.TempDir(), limit)
+				assert.NoError(t, err)
+				defer e.Close()
+
+				for _, url := range input {
+					assert.NoError(t, e.Push(url))
+				}
+				results := []Result{}
+				assert.NoError(t, e.EachResult(func(r Result) error {
+					results = append(results, r)
+					return nil
+				}))
+				assert.Equal(t, expected, results)
+			})
+		}
+	}
+}
+
+func TestExternal(t *testing.T) {
+	dir := t.TempDir()
+	cleaner := New(DefaultOptions())
+
+	e, err := cleaner.NewExternal(dir, 64)
+	assert.NoError(t, err)
+	for _, url := range []string{"http://b.com", "https://a.com/", "http://a.com", "https://b.com", "https://c.com", "https://a.com"} {
+		assert.NoError(t, e.Push(url))
+	}
+
+	var urls []string
+	assert.NoError(t, e.Each(func(url string) error {
+		urls = append(urls, url)
+		return nil
+	}))
+	assert.Equal(t, []string{"https://a.com", "https://b.com", "https://c.com"}, urls)
+
+	// Temporary files are removed
+	assert.NoError(t, e.Close())
+	entries, _ := os.ReadDir(dir)
+	assert.Empty(t, entries)
+}
+
+func TestExternalSeen(t *testing.T) {
+	seen, err := OpenSeenSet(t.TempDir() + "/seen.db")
+	assert.NoError(t, err)
+	defer seen.Close()
+	seen.Add("https://a.com")
+
+	opts := DefaultOptions()
+	opts.Seen = seen
+	e, _ := New(opts).NewExternal(t.TempDir(), 1)
+	defer e.Close()
+	for _, url := range []string{"https://b.com", "https://a.com", "http://c.com"} {
+		e.Push(url)
+	}
+
+	var urls []string
+	e.Each(func(url string) error {
+		urls = append(urls, url)
+		return nil
+	})
+	assert.Equal(t, []string{"https://b.com", "http://c.com"}, urls)
+	assert.True(t, seen.Contains("http://c.com"))
+}
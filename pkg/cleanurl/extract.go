@@ -0,0 +1,177 @@
+package cleanurl
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// urlPattern matches the value of a link attribute (href, src or action) or
+// an absolute URL anywhere in the text. Attribute values come first so that
+// an absolute URL inside one is matched as the attribute, exactly as quoted.
+var urlPattern = regexp.MustCompile(`(?i)\b(?:href|src|action)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'<>` + "`" + `]+))` +
+	`|\b(?:https?|ftp|wss?)://[^\s"'<>` + "`" + `\\^{}|]+`)
+
+// unescaper undoes the escaping URLs get in JSON, JavaScript and HTML, such
+// as https:\/\/example.com\/ and ?a=1&amp;b=2.
+var unescaper = strings.NewReplacer(
+	`\/`, "/",
+	`\u002F`, "/",
+	`\u002f`, "/",
+	`\u0026`, "&",
+	"&amp;", "&",
+	"&#x2F;", "/",
+	"&#x2f;", "/",
+	"&#47;", "/",
+)
+
+// baseTagPattern matches an HTML <base> element.
+var baseTagPattern = regexp.MustCompile(`(?i)<base\b[^>]*>`)
+
+// ExtractURLs finds the URLs in a piece of free-form text such as a log
+// line, HTML or minified JavaScript, in order of appearance. Absolute URLs
+// are found anywhere and lose surrounding punctuation like a trailing period
+// or an unbalanced closing parenthesis. The values of href, src and action
+// attributes are returned as written, even when relative, except for
+// fragments and non-navigational schemes such as javascript: and mailto:.
+// Relative values are resolved only after an absolute <base href>.
+func ExtractURLs(text string) []string {
+	var e Extractor
+	return e.Extract(text)
+}
+
+// Extractor is ExtractURLs for a document read piece by piece, such as line
+// by line, that resolves relative references to absolute URLs.
+type Extractor struct {
+	// Base is the URL that relative references are resolved against. Nil
+	// leaves them as written. An HTML <base href> replaces Base for the rest
+	// of the document.
+	Base *url.URL
+}
+
+// Extract returns the URLs in the next piece of the document, as ExtractURLs
+// does, with attribute values resolved against the base URL.
+func (e *Extractor) Extract(text string) []string {
+	var urls []string
+
+	text = unescaper.Replace(text)
+	baseTags := baseTagPattern.FindAllStringIndex(text, -1)
+
+	for _, match := range urlPattern.FindAllStringSubmatchIndex(text, -1) {
+		value, ok := attributeValue(text, match)
+		if !ok {
+			if found := trimTrailingPunctuation(text[match[0]:match[1]]); found != "" {
+				urls = append(urls, found)
+			}
+			continue
+		}
+
+		if inside(match[0], baseTags) {
+			if base, err := e.resolve(value); err == nil && base.IsAbs() && base.Host != "" {
+				e.Base = base
+			}
+			continue
+		}
+		if !isLink(value) {
+			continue
+		}
+		if e.Base != nil {
+			if resolved, err := e.resolve(value); err == nil {
+				value = resolved.String()
+			}
+		}
+		urls = append(urls, value)
+	}
+	return urls
+}
+
+// resolve parses ref and resolves it against the base URL, if any.
+func (e *Extractor) resolve(ref string) (*url.URL, error) {
+	u, err := url.Parse(ref)
+	if err != nil || e.Base == nil {
+		return u, err
+	}
+	return e.Base.ResolveReference(u), nil
+}
+
+// inside reports whether pos lies within one of spans.
+func inside(pos int, spans [][]int) bool {
+	for _, span := range spans {
+		if span[0] <= pos && pos < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveReference resolves ref against base when ref is clearly a relative
+// reference: a network-path reference such as //cdn.example.com/a.js, a
+// path-absolute one such as /api/v1/users, or a dot-relative one such as
+// ../img/x.png. Anything else is returned unchanged, including schemeless
+// input like example.com/path, which is taken to be a host rather than a
+// relative path.
+func ResolveReference(base *url.URL, ref string) string {
+	if base == nil || !(strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, "./") || strings.HasPrefix(ref, "../")) {
+		return ref
+	}
+	u, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// attributeValue returns the trimmed attribute value captured by match, or
+// false when match is a bare URL.
+func attributeValue(text string, match []int) (string, bool) {
+	for group := 1; group <= 3; group++ {
+		if start := match[2*group]; start != -1 {
+			return strings.TrimSpace(text[start:match[2*group+1]]), true
+		}
+	}
+	return "", false
+}
+
+// skippedSchemes are attribute schemes that do not point to a resource.
+var skippedSchemes = []string{"javascript:", "mailto:", "tel:", "data:", "about:"}
+
+// isLink reports whether an attribute value is worth extracting.
+func isLink(value string) bool {
+	if value == "" || strings.HasPrefix(value, "#") {
+		return false
+	}
+	lower := strings.ToLower(value)
+	for _, scheme := range skippedSchemes {
+		if strings.HasPrefix(lower, scheme) {
+			return false
+		}
+	}
+	return true
+}
+
+// trimTrailingPunctuation removes the sentence punctuation that free-form
+// text puts after a URL, as well as closing brackets without an opening one
+// in the URL, so "(see https://example.com/a_(b))." gives
+// https://example.com/a_(b). HTML entities for quotes and angle brackets end
+// the URL.
+func trimTrailingPunctuation(url string) string {
+	for _, entity := range []string{"&quot;", "&lt;", "&gt;", "&#39;"} {
+		if idx := strings.Index(url, entity); idx != -1 {
+			url = url[:idx]
+		}
+	}
+
+	for url != "" {
+		last := url[len(url)-1]
+		switch {
+		case strings.IndexByte(".,;:!?*", last) != -1:
+			url = url[:len(url)-1]
+		case last == ')' && strings.Count(url, "(") < strings.Count(url, ")"),
+			last == ']' && strings.Count(url, "[") < strings.Count(url, "]"):
+			url = url[:len(url)-1]
+		default:
+			return url
+		}
+	}
+	return url
+}
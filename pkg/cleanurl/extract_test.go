@@ -0,0 +1,223 @@
+package cleanurl
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractURLs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:     "Log line",
+			input:    `127.0.0.1 - - [10/Oct/2023:13:55:36] "GET /x HTTP/1.1" 200 2326 "https://example.com/ref?a=1" "Mozilla/5.0"`,
+			expected: []string{"https://example.com/ref?a=1"},
+		},
+		{
+			name:     "Sentence punctuation",
+			input:    "See https://example.com/docs, or (https://test.com/a). Also https://x.com/end.",
+			expected: []string{"https://example.com/docs", "https://test.com/a", "https://x.com/end"},
+		},
+		{
+			name:     "Balanced parentheses are kept",
+			input:    "(see https://en.wikipedia.org/wiki/Go_(programming_language))",
+			expected: []string{"https://en.wikipedia.org/wiki/Go_(programming_language)"},
+		},
+		{
+			name:     "IPv6 literal",
+			input:    "connect to http://[2001:db8::1]:8080/x.",
+			expected: []string{"http://[2001:db8::1]:8080/x"},
+		},
+		{
+			name:     "HTML attributes",
+			input:    `<a href="/about">About</a><img src='https://cdn.example.com/a.png'><form action=/login method=post>`,
+			expected: []string{"/about", "https://cdn.example.com/a.png", "/login"},
+		},
+		{
+			name:     "HTML entities",
+			input:    `<a HREF="https://example.com/?a=1&amp;b=2">https://example.com/?c=3&amp;d=4</a>`,
+			expected: []string{"https://example.com/?a=1&b=2", "https://example.com/?c=3&d=4"},
+		},
+		{
+			name:     "Non-navigational attributes are skipped",
+			input:    `<a href="#top"></a><a href="javascript:void(0)"></a><a href="mailto:a@b.c"></a><a href=""></a>`,
+			expected: nil,
+		},
+		{
+			name:     "JSON escaped slashes",
+			input:    `{"url":"https:\/\/example.com\/path?q=1","next":"https://test.com/a&b"}`,
+			expected: []string{"https://example.com/path?q=1", "https://test.com/a&b"},
+		},
+		{
+			name:     "Minified JavaScript",
+			input:    `fetch("https://api.example.com/v1/items?id="+id).then(r=>r.json());var u='wss://ws.example.com/feed';`,
+			expected: []string{"https://api.example.com/v1/items?id=", "wss://ws.example.com/feed"},
+		},
+		{
+			name:     "No URLs",
+			input:    "nothing to see here: example.com",
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ExtractURLs(tt.input))
+		})
+	}
+}
+
+func TestTrimTrailingPunctuation(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Trailing period",
+			input:    "https://example.com.",
+			expected: "https://example.com",
+		},
+		{
+			name:     "Several marks",
+			input:    "https://example.com/a?!",
+			expected: "https://example.com/a",
+		},
+		{
+			name:     "Unbalanced parenthesis",
+			input:    "https://example.com/a)",
+			expected: "https://example.com/a",
+		},
+		{
+			name:     "Unbalanced bracket",
+			input:    "https://example.com/a]",
+			expected: "https://example.com/a",
+		},
+		{
+			name:     "HTML quote entity",
+			input:    "https://example.com/a&quot;>",
+			expected: "https://example.com/a",
+		},
+		{
+			name:     "Nothing to trim",
+			input:    "https://example.com/a_(b)",
+			expected: "https://example.com/a_(b)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, trimTrailingPunctuation(tt.input))
+		})
+	}
+}
+
+func TestExtractorBase(t *testing.T) {
+	base, _ := url.Parse("https://example.com/page/")
+
+	tests := []struct {
+		name     string
+		base     *url.URL
+		input    []string
+		expected []string
+	}{
+		{
+			name:     "Relative references",
+			base:     base,
+			input:    []string{`<a href="/api/v1/users">`, `<img src="../img/x.png"><script src="//cdn.example.com/a.js">`, `<a href="next?p=2#top">`},
+			expected: []string{"https://example.com/api/v1/users", "https://example.com/img/x.png", "https://cdn.example.com/a.js", "https://example.com/page/next?p=2#top"},
+		},
+		{
+			name:     "Absolute URLs are unchanged",
+			base:     base,
+			input:    []string{`<a href="http://other.com/a">`, `see https://other.com/b.`},
+			expected: []string{"http://other.com/a", "https://other.com/b"},
+		},
+		{
+			name:     "Base tag overrides the base URL for the rest of the document",
+			base:     base,
+			input:    []string{`<a href="/before">`, `<head><base href="https://docs.example.org/v2/"></head>`, `<a href="intro.html">`},
+			expected: []string{"https://example.com/before", "https://docs.example.org/v2/intro.html"},
+		},
+		{
+			name:     "Relative base tag is resolved against the base URL",
+			base:     base,
+			input:    []string{`<base href="/docs/"><a href='a.html'>`},
+			expected: []string{"https://example.com/docs/a.html"},
+		},
+		{
+			name:     "Base tag without a base URL",
+			input:    []string{`<a href="/before">`, `<BASE HREF="https://example.net/">`, `<a href="/after">`},
+			expected: []string{"/before", "https://example.net/after"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := Extractor{Base: tt.base}
+			var result []string
+			for _, line := range tt.input {
+				result = append(result, e.Extract(line)...)
+			}
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestResolveReference(t *testing.T) {
+	base, _ := url.Parse("https://example.com/page/")
+
+	tests := []struct {
+		name     string
+		base     *url.URL
+		input    string
+		expected string
+	}{
+		{
+			name:     "Path-absolute reference",
+			base:     base,
+			input:    "/api/v1/users",
+			expected: "https://example.com/api/v1/users",
+		},
+		{
+			name:     "Dot-relative reference",
+			base:     base,
+			input:    "../img/x.png",
+			expected: "https://example.com/img/x.png",
+		},
+		{
+			name:     "Network-path reference",
+			base:     base,
+			input:    "//cdn.example.com/a.js",
+			expected: "https://cdn.example.com/a.js",
+		},
+		{
+			name:     "Absolute URL",
+			base:     base,
+			input:    "http://other.com/a",
+			expected: "http://other.com/a",
+		},
+		{
+			name:     "Schemeless host",
+			base:     base,
+			input:    "example.org/path",
+			expected: "example.org/path",
+		},
+		{
+			name:     "No base URL",
+			input:    "/api/v1/users",
+			expected: "/api/v1/users",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ResolveReference(tt.base, tt.input))
+		})
+	}
+}
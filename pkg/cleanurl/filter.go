@@ -0,0 +1,81 @@
+package cleanurl
+
+import (
+	"path"
+	"strings"
+)
+
+// extensionCategories are the groups of file extensions accepted by
+// Options.Drop.
+var extensionCategories = map[string][]string{
+	"images": {"png", "jpg", "jpeg", "gif", "bmp", "webp", "svg", "ico", "tif", "tiff", "avif", "heic", "apng"},
+	"fonts":  {"woff", "woff2", "ttf", "otf", "eot"},
+	"styles": {"css", "scss", "sass", "less"},
+	"media":  {"mp4", "webm", "mkv", "avi", "mov", "wmv", "flv", "m4v", "ogv", "mp3", "wav", "ogg", "oga", "m4a", "aac", "flac"},
+	"docs":   {"pdf", "doc", "docx", "xls", "xlsx", "ppt", "pptx", "odt", "ods", "odp", "rtf", "epub"},
+}
+
+// CategoryExtensions returns the file extensions of a category accepted by
+// Options.Drop: images, fonts, styles, media or docs.
+func CategoryExtensions(category string) ([]string, bool) {
+	extensions, ok := extensionCategories[strings.ToLower(category)]
+	return extensions, ok
+}
+
+// extensionFilter keeps or drops URLs by the file extension of their path.
+type extensionFilter struct {
+	include map[string]bool
+	exclude map[string]bool
+}
+
+// newExtensionFilter builds the filter for opts, or returns nil when no URL
+// is filtered. Unknown categories are ignored.
+func newExtensionFilter(opts Options) *extensionFilter {
+	if len(opts.IncludeExt) == 0 && len(opts.ExcludeExt) == 0 && len(opts.Drop) == 0 {
+		return nil
+	}
+
+	f := &extensionFilter{exclude: extensionSet(opts.ExcludeExt)}
+	if len(opts.IncludeExt) > 0 {
+		f.include = extensionSet(opts.IncludeExt)
+	}
+	for _, category := range opts.Drop {
+		extensions, _ := CategoryExtensions(category)
+		for _, ext := range extensions {
+			f.exclude[ext] = true
+		}
+	}
+	return f
+}
+
+// extensionSet returns extensions lowercased and without a leading ".".
+func extensionSet(extensions []string) map[string]bool {
+	set := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		set[strings.ToLower(strings.TrimPrefix(ext, "."))] = true
+	}
+	return set
+}
+
+// keeps reports whether url passes the filter. A nil filter keeps all URLs.
+func (f *extensionFilter) keeps(url string) bool {
+	if f == nil {
+		return true
+	}
+	ext := pathExtension(url)
+	if f.include != nil && !f.include[ext] {
+		return false
+	}
+	return !f.exclude[ext]
+}
+
+// pathExtension returns the lowercase extension of the last segment of the
+// path of url, without the ".", ignoring the query and fragment, so that
+// /logo.png?v=3 gives "png". It is empty when there is none.
+func pathExtension(url string) string {
+	start, end, ok := pathBounds(url)
+	if !ok {
+		return ""
+	}
+	return strings.ToLower(strings.TrimPrefix(path.Ext(url[start:end]), "."))
+}
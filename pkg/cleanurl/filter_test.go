@@ -0,0 +1,120 @@
+package cleanurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathExtension(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Extension",
+			input:    "https://example.com/static/logo.PNG",
+			expected: "png",
+		},
+		{
+			name:     "Query and fragment are ignored",
+			input:    "https://example.com/logo.png?v=3.1#x.css",
+			expected: "png",
+		},
+		{
+			name:     "Only the last segment counts",
+			input:    "https://example.com/v1.2/users",
+			expected: "",
+		},
+		{
+			name:     "Host is not an extension",
+			input:    "example.com",
+			expected: "",
+		},
+		{
+			name:     "Schemeless URL",
+			input:    "cdn.example.com/fonts/a.woff2",
+			expected: "woff2",
+		},
+		{
+			name:     "Extension in the query only",
+			input:    "https://example.com/download?file=report.pdf",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, pathExtension(tt.input))
+		})
+	}
+}
+
+func TestCleanExtensionFilters(t *testing.T) {
+	input := []string{
+		"https://example.com/",
+		"https://example.com/logo.png?v=3",
+		"https://example.com/app.js",
+		"https://example.com/site.css",
+		"https://example.com/fonts/a.woff2",
+		"https://example.com/login.php?next=/a.png",
+		"https://example.com/report.PDF",
+		"https://example.com/intro.mp4",
+	}
+
+	tests := []struct {
+		name     string
+		opts     func(*Options)
+		expected []string
+	}{
+		{
+			name:     "Exclude extensions",
+			opts:     func(o *Options) { o.ExcludeExt = []string{".png", "JS"} },
+			expected: []string{"https://example.com", "https://example.com/site.css", "https://example.com/fonts/a.woff2", "https://example.com/login.php?next=/a.png", "https://example.com/report.PDF", "https://example.com/intro.mp4"},
+		},
+		{
+			name:     "Include extensions",
+			opts:     func(o *Options) { o.IncludeExt = []string{"php", "js"} },
+			expected: []string{"https://example.com/app.js", "https://example.com/login.php?next=/a.png"},
+		},
+		{
+			name:     "Include and exclude",
+			opts:     func(o *Options) { o.IncludeExt, o.ExcludeExt = []string{"php", "js"}, []string{"js"} },
+			expected: []string{"https://example.com/login.php?next=/a.png"},
+		},
+		{
+			name:     "Drop categories",
+			opts:     func(o *Options) { o.Drop = []string{"images", "fonts", "styles", "media", "docs", "unknown"} },
+			expected: []string{"https://example.com", "https://example.com/app.js", "https://example.com/login.php?next=/a.png"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			tt.opts(&opts)
+			cleaner := New(opts)
+			assert.Equal(t, tt.expected, cleaner.Clean(input))
+
+			// Streams filter the same URLs
+			s := cleaner.NewStream()
+			var streamed []string
+			for _, url := range input {
+				if cleaned, ok := s.Push(url); ok {
+					streamed = append(streamed, cleaned)
+				}
+			}
+			assert.Equal(t, tt.expected, streamed)
+		})
+	}
+}
+
+func TestCategoryExtensions(t *testing.T) {
+	images, ok := CategoryExtensions("Images")
+	assert.True(t, ok)
+	assert.Contains(t, images, "png")
+
+	_, ok = CategoryExtensions("scripts")
+	assert.False(t, ok)
+}
@@ -0,0 +1,105 @@
+package cleanurl
+
+import (
+	"net/netip"
+	"strings"
+)
+
+// splitHostPort splits an authority without userinfo into host and port.
+// Bracketed IPv6 literals keep their brackets, and an unbracketed host with
+// more than one colon is taken to be a bare IPv6 address without a port.
+func splitHostPort(hostport string) (host, port string) {
+	if strings.HasPrefix(hostport, "[") {
+		end := strings.Index(hostport, "]")
+		if end == -1 {
+			return hostport, ""
+		}
+		host, rest := hostport[:end+1], hostport[end+1:]
+		return host, strings.TrimPrefix(rest, ":")
+	}
+	if strings.Count(hostport, ":") > 1 {
+		return hostport, ""
+	}
+	if idx := strings.LastIndex(hostport, ":"); idx != -1 {
+		return hostport[:idx], hostport[idx+1:]
+	}
+	return hostport, ""
+}
+
+// canonicalHost rewrites an IPv6 literal into its canonical compressed,
+// lowercase, bracketed form, keeping any zone identifier percent-encoded.
+// Other hosts are returned unchanged.
+func canonicalHost(host string) string {
+	addr, ok := parseIPv6Literal(host)
+	if !ok {
+		return host
+	}
+	return formatIPv6Literal(addr)
+}
+
+// comparisonHost is canonicalHost for deduplication: IPv4-mapped IPv6
+// addresses are additionally unmapped so that they match the IPv4 host.
+func comparisonHost(host string) string {
+	addr, ok := parseIPv6Literal(host)
+	if !ok {
+		return host
+	}
+	if addr.Is4In6() {
+		return addr.Unmap().String()
+	}
+	return formatIPv6Literal(addr)
+}
+
+// parseIPv6Literal parses a bracketed or bare IPv6 address, with an optional
+// zone identifier written as "%zone" or "%25zone".
+func parseIPv6Literal(host string) (netip.Addr, bool) {
+	inner := host
+	if strings.HasPrefix(inner, "[") && strings.HasSuffix(inner, "]") {
+		inner = inner[1 : len(inner)-1]
+	}
+	if !strings.Contains(inner, ":") {
+		return netip.Addr{}, false
+	}
+	inner = strings.Replace(inner, "%25", "%", 1)
+	addr, err := netip.ParseAddr(inner)
+	if err != nil || !addr.Is6() {
+		return netip.Addr{}, false
+	}
+	return addr, true
+}
+
+func formatIPv6Literal(addr netip.Addr) string {
+	literal := addr.WithZone("").String()
+	if zone := addr.Zone(); zone != "" {
+		literal += "%25" + zone
+	}
+	return "[" + literal + "]"
+}
+
+// canonicalizeURLHost rewrites the host of url with comparisonHost, so that
+// equivalent IPv6 literals compare equal. The scheme is optional.
+func canonicalizeURLHost(url string) string {
+	if !strings.Contains(url, "[") && strings.Count(url, ":") < 2 {
+		return url
+	}
+
+	start := 0
+	if idx := strings.Index(url, "://"); idx != -1 {
+		start = idx + len("://")
+	}
+	end := len(url)
+	if idx := strings.IndexAny(url[start:], "/?#"); idx != -1 {
+		end = start + idx
+	}
+	hostStart := start
+	if idx := strings.LastIndex(url[start:end], "@"); idx != -1 {
+		hostStart = start + idx + 1
+	}
+
+	host, port := splitHostPort(url[hostStart:end])
+	host = comparisonHost(host)
+	if port != "" {
+		host += ":" + port
+	}
+	return url[:hostStart] + host + url[end:]
+}
@@ -0,0 +1,84 @@
+package cleanurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitHostPort(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		expectedHost string
+		expectedPort string
+	}{
+		{name: "Host only", input: "example.com", expectedHost: "example.com"},
+		{name: "Host and port", input: "example.com:8080", expectedHost: "example.com", expectedPort: "8080"},
+		{name: "IPv6 literal", input: "[::1]", expectedHost: "[::1]"},
+		{name: "IPv6 literal with port", input: "[2001:db8::1]:8080", expectedHost: "[2001:db8::1]", expectedPort: "8080"},
+		{name: "IPv6 literal with zone", input: "[fe80::1%25en0]:443", expectedHost: "[fe80::1%25en0]", expectedPort: "443"},
+		{name: "Bare IPv6 address", input: "2001:db8::1", expectedHost: "2001:db8::1"},
+		{name: "Unterminated bracket", input: "[::1", expectedHost: "[::1"},
+		{name: "Empty input", input: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			host, port := splitHostPort(tt.input)
+			assert.Equal(t, tt.expectedHost, host)
+			assert.Equal(t, tt.expectedPort, port)
+		})
+	}
+}
+
+func TestComparisonHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Domain is unchanged", input: "example.com", expected: "example.com"},
+		{name: "IPv4 is unchanged", input: "192.168.1.1", expected: "192.168.1.1"},
+		{name: "Compressed form", input: "[2001:0DB8:0000:0000:0000:0000:0000:0001]", expected: "[2001:db8::1]"},
+		{name: "Loopback", input: "[0:0:0:0:0:0:0:1]", expected: "[::1]"},
+		{name: "Zone identifier", input: "[FE80::1%25eth0]", expected: "[fe80::1%25eth0]"},
+		{name: "Unencoded zone identifier", input: "[fe80::1%eth0]", expected: "[fe80::1%25eth0]"},
+		{name: "IPv4-mapped dotted", input: "[::ffff:192.0.2.1]", expected: "192.0.2.1"},
+		{name: "IPv4-mapped hex", input: "[::ffff:c000:201]", expected: "192.0.2.1"},
+		{name: "Invalid literal is unchanged", input: "[not:an:ip]", expected: "[not:an:ip]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := comparisonHost(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestCanonicalHost(t *testing.T) {
+	assert.Equal(t, "[::ffff:192.0.2.1]", canonicalHost("[::ffff:c000:201]"))
+	assert.Equal(t, "[2001:db8::1]", canonicalHost("[2001:DB8:0::1]"))
+	assert.Equal(t, "example.com", canonicalHost("example.com"))
+}
+
+func TestCanonicalizeURLHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{name: "Regular URL", input: "https://example.com:8080/path", expected: "https://example.com:8080/path"},
+		{name: "IPv6 with port and path", input: "http://[2001:DB8:0::1]:8080/x?a=b", expected: "http://[2001:db8::1]:8080/x?a=b"},
+		{name: "IPv6 with userinfo", input: "http://user@[0::1]/", expected: "http://user@[::1]/"},
+		{name: "No scheme", input: "[::ffff:192.0.2.1]:80/a", expected: "192.0.2.1:80/a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := canonicalizeURLHost(tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
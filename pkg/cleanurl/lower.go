@@ -0,0 +1,85 @@
+package cleanurl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LowerMode selects which part of a URL is converted to lowercase.
+type LowerMode int
+
+const (
+	// LowerNone leaves the URL untouched.
+	LowerNone LowerMode = iota
+	// LowerHost lowercases only the scheme and host, preserving the case of
+	// case-sensitive paths, queries and fragments.
+	LowerHost
+	// LowerAll lowercases the whole URL.
+	LowerAll
+)
+
+var lowerModeNames = map[LowerMode]string{
+	LowerNone: "none",
+	LowerHost: "host",
+	LowerAll:  "all",
+}
+
+// String returns the flag value for m.
+func (m LowerMode) String() string {
+	return lowerModeNames[m]
+}
+
+// Set parses a flag value into m. It accepts "none", "host" and "all", and
+// "false" and "true" as aliases of "none" and "host".
+func (m *LowerMode) Set(value string) error {
+	switch strings.ToLower(value) {
+	case "none", "false":
+		*m = LowerNone
+	case "host", "true":
+		*m = LowerHost
+	case "all":
+		*m = LowerAll
+	default:
+		return fmt.Errorf("invalid lower mode %q (want none, host or all)", value)
+	}
+	return nil
+}
+
+// Type returns the flag value type shown in usage messages.
+func (m *LowerMode) Type() string {
+	return "mode"
+}
+
+func convertHostToLowercase(urls []string) []string {
+	if len(urls) == 0 {
+		return []string{}
+	}
+	var result []string
+	for _, url := range urls {
+		result = append(result, lowercaseSchemeAndHost(url))
+	}
+	return result
+}
+
+// lowercaseSchemeAndHost lowercases the scheme and host of url, leaving the
+// userinfo, path, query and fragment untouched. It works on the raw string so
+// that surrounding quotes do not prevent the scheme from being found.
+func lowercaseSchemeAndHost(url string) string {
+	start := 0
+	if idx := strings.Index(url, "://"); idx != -1 {
+		start = idx + len("://")
+	}
+
+	end := len(url)
+	if idx := strings.IndexAny(url[start:], "/?#"); idx != -1 {
+		end = start + idx
+	}
+
+	// Skip userinfo, which is case-sensitive
+	hostStart := start
+	if idx := strings.LastIndex(url[start:end], "@"); idx != -1 {
+		hostStart = start + idx + 1
+	}
+
+	return strings.ToLower(url[:start]) + url[start:hostStart] + strings.ToLower(url[hostStart:end]) + url[end:]
+}
@@ -0,0 +1,135 @@
+package cleanurl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// matchComponents are the component prefixes accepted by ParseMatcher.
+var matchComponents = []string{"url", "scheme", "host", "port", "path", "query", "fragment", "param"}
+
+// Matcher is a regular expression matched against a whole URL or against one
+// of its components.
+type Matcher struct {
+	component string
+	param     string
+	re        *regexp.Regexp
+}
+
+// ParseMatcher parses a Go regular expression optionally prefixed with the
+// component it applies to: "host:", "path:", "query:" (the raw query),
+// "scheme:", "port:", "fragment:", or "param:name:" for the values of the
+// query parameter name. Without a known prefix, or with "url:", it applies
+// to the whole URL.
+func ParseMatcher(spec string) (*Matcher, error) {
+	m := &Matcher{component: "url"}
+	expr := spec
+
+	if prefix, rest, ok := strings.Cut(spec, ":"); ok {
+		for _, component := range matchComponents {
+			if prefix == component {
+				m.component, expr = component, rest
+				break
+			}
+		}
+	}
+	if m.component == "param" {
+		name, rest, ok := strings.Cut(expr, ":")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid matcher %q: want param:name:regexp", spec)
+		}
+		m.param, expr = name, rest
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid matcher %q: %w", spec, err)
+	}
+	m.re = re
+	return m, nil
+}
+
+// String returns the matcher as it was parsed.
+func (m *Matcher) String() string {
+	switch m.component {
+	case "url":
+		return "url:" + m.re.String()
+	case "param":
+		return "param:" + m.param + ":" + m.re.String()
+	}
+	return m.component + ":" + m.re.String()
+}
+
+// Match reports whether the matcher's component of raw matches. A param
+// matcher matches if any value of the parameter does, and never when the
+// parameter is absent.
+func (m *Matcher) Match(raw string) bool {
+	return m.match(raw, ParseComponents(raw))
+}
+
+func (m *Matcher) match(raw string, c Components) bool {
+	switch m.component {
+	case "scheme":
+		return m.re.MatchString(c.Scheme)
+	case "host":
+		return m.re.MatchString(c.Host)
+	case "port":
+		return m.re.MatchString(c.Port)
+	case "path":
+		return m.re.MatchString(c.Path)
+	case "query":
+		return m.re.MatchString(c.RawQuery)
+	case "fragment":
+		return m.re.MatchString(c.Fragment)
+	case "param":
+		for _, value := range c.Query[m.param] {
+			if m.re.MatchString(value) {
+				return true
+			}
+		}
+		return false
+	}
+	return m.re.MatchString(raw)
+}
+
+// matches applies the Match and Exclude options to a cleaned URL: it must
+// match at least one Match matcher, if any, and no Exclude matcher.
+func (c *Cleaner) matches(url string) bool {
+	if len(c.opts.Match) == 0 && len(c.opts.Exclude) == 0 {
+		return true
+	}
+	components := ParseComponents(url)
+
+	if len(c.opts.Match) > 0 {
+		matched := false
+		for _, m := range c.opts.Match {
+			if m.match(url, components) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	for _, m := range c.opts.Exclude {
+		if m.match(url, components) {
+			return false
+		}
+	}
+	return true
+}
+
+// keeps reports whether a cleaned URL passes the extension filters, the
+// matchers and the scope.
+func (c *Cleaner) keeps(url string) bool {
+	return c.filter.keeps(url) && c.matches(url) && c.inScope(url)
+}
+
+// keepsInput reports whether an input URL passes keeps once cleaned, so that
+// the domain modes only list hosts of URLs the URL output would keep.
+func (c *Cleaner) keepsInput(url string) bool {
+	cleaned, _ := c.cleanOne(url)
+	return c.keeps(cleaned)
+}
@@ -0,0 +1,164 @@
+package cleanurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "Whole URL",
+			input:    `\.php$`,
+			expected: `url:\.php$`,
+		},
+		{
+			name:     "Whole URL with a scheme in the regexp",
+			input:    `https://api\.`,
+			expected: `url:https://api\.`,
+		},
+		{
+			name:     "Component",
+			input:    `host:^api\.`,
+			expected: `host:^api\.`,
+		},
+		{
+			name:     "Explicit whole URL",
+			input:    `url:path:`,
+			expected: `url:path:`,
+		},
+		{
+			name:     "Parameter",
+			input:    `param:id:^[0-9]+$`,
+			expected: `param:id:^[0-9]+$`,
+		},
+		{
+			name:    "Parameter without a name",
+			input:   `param::x`,
+			wantErr: true,
+		},
+		{
+			name:    "Parameter without a regexp separator",
+			input:   `param:id`,
+			wantErr: true,
+		},
+		{
+			name:    "Invalid regexp",
+			input:   `path:(`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := ParseMatcher(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, m.String())
+		})
+	}
+}
+
+func TestMatcherMatch(t *testing.T) {
+	url := "https://api.example.com:8443/v1/users?id=42&tag=a&tag=b#top"
+
+	tests := []struct {
+		spec     string
+		expected bool
+	}{
+		{spec: `/v1/`, expected: true},
+		{spec: `scheme:^https$`, expected: true},
+		{spec: `host:^api\.`, expected: true},
+		{spec: `host:8443`, expected: false},
+		{spec: `port:^8443$`, expected: true},
+		{spec: `path:^/v1/users$`, expected: true},
+		{spec: `path:id`, expected: false},
+		{spec: `query:tag=b`, expected: true},
+		{spec: `fragment:^top$`, expected: true},
+		{spec: `param:id:^[0-9]+$`, expected: true},
+		{spec: `param:tag:^b$`, expected: true},
+		{spec: `param:missing:.*`, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			m, err := ParseMatcher(tt.spec)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, m.Match(url))
+		})
+	}
+}
+
+func TestCleanMatchers(t *testing.T) {
+	mustParse := func(specs ...string) []*Matcher {
+		var matchers []*Matcher
+		for _, spec := range specs {
+			m, err := ParseMatcher(spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			matchers = append(matchers, m)
+		}
+		return matchers
+	}
+
+	input := []string{
+		"HTTPS://API.Example.com/v1/users/",
+		"https://www.example.com/about",
+		"https://api.example.com/v1/items?id=1",
+		"http://api.example.com/v1/items?id=1",
+		"https://cdn.example.com/v1/app.js",
+	}
+
+	tests := []struct {
+		name     string
+		match    []string
+		exclude  []string
+		expected []string
+	}{
+		{
+			name:     "Matchers see the cleaned URL",
+			match:    []string{`host:^api\.example\.com$`},
+			expected: []string{"https://api.example.com/v1/users", "https://api.example.com/v1/items?id=1"},
+		},
+		{
+			name:     "Any match keeps the URL",
+			match:    []string{`path:/about$`, `param:id:.`},
+			expected: []string{"https://www.example.com/about", "https://api.example.com/v1/items?id=1"},
+		},
+		{
+			name:     "Exclusions win over matches",
+			match:    []string{`/v1/`},
+			exclude:  []string{`path:\.js$`, `query:id=`},
+			expected: []string{"https://api.example.com/v1/users"},
+		},
+		{
+			name:     "Trailing slash is removed before matching",
+			match:    []string{`path:users$`},
+			expected: []string{"https://api.example.com/v1/users"},
+		},
+		{
+			name:     "HTTP twin of an excluded HTTPS URL is kept",
+			exclude:  []string{`scheme:^https$`},
+			expected: []string{"http://api.example.com/v1/items?id=1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.Match = mustParse(tt.match...)
+			opts.Exclude = mustParse(tt.exclude...)
+			assert.Equal(t, tt.expected, New(opts).Clean(input))
+		})
+	}
+}
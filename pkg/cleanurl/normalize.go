@@ -0,0 +1,175 @@
+package cleanurl
+
+import (
+	"net/url"
+	"strings"
+)
+
+// defaultPorts maps schemes to the port implied when none is given.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+	"ftp":   "21",
+}
+
+func normalizeURLs(urls []string) []string {
+	if len(urls) == 0 {
+		return []string{}
+	}
+	var result []string
+	for _, u := range urls {
+		result = append(result, normalizeRFC3986(u))
+	}
+	return result
+}
+
+// normalizeRFC3986 applies the syntax- and scheme-based normalizations of
+// RFC 3986 section 6.2: it lowercases the scheme and host, uppercases
+// percent-encoding hex digits, decodes percent-encoded unreserved characters,
+// removes dot segments and drops the default port. URLs that cannot be parsed
+// or have no host are returned unchanged.
+func normalizeRFC3986(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return raw
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	host := strings.ToLower(u.Hostname())
+	if strings.Contains(host, ":") {
+		host = canonicalHost("[" + host + "]")
+	}
+	if port := u.Port(); port != "" && port != defaultPorts[scheme] {
+		host += ":" + port
+	}
+
+	var b strings.Builder
+	b.WriteString(scheme)
+	b.WriteString("://")
+	if u.User != nil {
+		b.WriteString(normalizePercentEncoding(u.User.String()))
+		b.WriteByte('@')
+	}
+	b.WriteString(host)
+
+	path := removeDotSegments(normalizePercentEncoding(u.EscapedPath()))
+	if path == "" {
+		path = "/"
+	}
+	b.WriteString(path)
+
+	if u.ForceQuery || u.RawQuery != "" {
+		b.WriteByte('?')
+		b.WriteString(normalizePercentEncoding(u.RawQuery))
+	}
+	if u.Fragment != "" {
+		b.WriteByte('#')
+		b.WriteString(normalizePercentEncoding(u.EscapedFragment()))
+	}
+	return b.String()
+}
+
+// extractParsedDomain is the net/url based counterpart of extractDomain. It
+// ignores userinfo and fragments, and falls back to extractDomain for input
+// that does not parse as an absolute URL with a host.
+func extractParsedDomain(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return extractDomain(raw)
+	}
+	host := u.Hostname()
+	if strings.Contains(host, ":") {
+		return comparisonHost("[" + host + "]")
+	}
+	return strings.TrimPrefix(host, "www.")
+}
+
+// normalizePercentEncoding decodes percent-encoded unreserved characters and
+// uppercases the hex digits of every other percent-encoded octet.
+func normalizePercentEncoding(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
+		}
+		c := unhex(s[i+1])<<4 | unhex(s[i+2])
+		if isUnreserved(c) {
+			b.WriteByte(c)
+		} else {
+			b.WriteByte('%')
+			b.WriteString(strings.ToUpper(s[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return b.String()
+}
+
+// removeDotSegments implements the algorithm of RFC 3986 section 5.2.4.
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+	var out []string
+	in := path
+	for in != "" {
+		switch {
+		case strings.HasPrefix(in, "../"):
+			in = in[3:]
+		case strings.HasPrefix(in, "./"):
+			in = in[2:]
+		case strings.HasPrefix(in, "/./"):
+			in = in[2:]
+		case in == "/.":
+			in = "/"
+		case strings.HasPrefix(in, "/../"):
+			in = in[3:]
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case in == "/..":
+			in = "/"
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case in == "." || in == "..":
+			in = ""
+		default:
+			// Move the first segment, including its leading slash, to the output
+			end := strings.IndexByte(in[1:], '/')
+			if end == -1 {
+				out = append(out, in)
+				in = ""
+			} else {
+				out = append(out, in[:end+1])
+				in = in[end+1:]
+			}
+		}
+	}
+	return strings.Join(out, "")
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
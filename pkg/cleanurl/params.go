@@ -0,0 +1,91 @@
+package cleanurl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Representative selects which of the URLs merged by DedupeParams or
+// CollapsePaths is output.
+type Representative int
+
+const (
+	// KeepFirst keeps the first URL seen.
+	KeepFirst Representative = iota
+	// KeepLast keeps the last URL seen, at the position of the first.
+	KeepLast
+	// KeepShortest keeps the shortest URL, the first one among equals.
+	KeepShortest
+	// KeepLongest keeps the longest URL, the first one among equals.
+	KeepLongest
+)
+
+var representativeNames = map[Representative]string{
+	KeepFirst:    "first",
+	KeepLast:     "last",
+	KeepShortest: "shortest",
+	KeepLongest:  "longest",
+}
+
+// String returns the flag value for k.
+func (k Representative) String() string {
+	return representativeNames[k]
+}
+
+// Set parses a flag value into k. It accepts "first", "last", "shortest" and
+// "longest".
+func (k *Representative) Set(value string) error {
+	for representative, name := range representativeNames {
+		if strings.EqualFold(value, name) {
+			*k = representative
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid representative %q (want first, last, shortest or longest)", value)
+}
+
+// Type returns the flag value type shown in usage messages.
+func (k *Representative) Type() string {
+	return "which"
+}
+
+// replaces reports whether candidate should replace current as the
+// representative of their pattern.
+func (k Representative) replaces(current, candidate string) bool {
+	switch k {
+	case KeepLast:
+		return true
+	case KeepShortest:
+		return len(candidate) < len(current)
+	case KeepLongest:
+		return len(candidate) > len(current)
+	}
+	return false
+}
+
+// paramsKey reduces a URL to what DedupeParams compares: everything before
+// the query, followed by the sorted, unique names of its query parameters.
+// Parameter values and the fragment are dropped, so /item?id=1 and
+// /item?id=2#top share the key /item?id.
+func paramsKey(url string) string {
+	before, query, _, ok := splitQuery(url)
+	if !ok {
+		before, _, _ = strings.Cut(url, "#")
+		return before
+	}
+
+	seen := make(map[string]bool)
+	var names []string
+	for _, param := range strings.Split(query, "&") {
+		if name := paramName(param); param != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return before
+	}
+	sort.Strings(names)
+	return before + "?" + strings.Join(names, "&")
+}
@@ -0,0 +1,148 @@
+package cleanurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamsKey(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Values are dropped",
+			input:    "https://example.com/item?id=1",
+			expected: "https://example.com/item?id",
+		},
+		{
+			name:     "Names are sorted and unique",
+			input:    "https://example.com/search?q=a&page=2&q=b",
+			expected: "https://example.com/search?page&q",
+		},
+		{
+			name:     "Encoded names are decoded",
+			input:    "https://example.com/item?%69d=1",
+			expected: "https://example.com/item?id",
+		},
+		{
+			name:     "Fragment is dropped",
+			input:    "https://example.com/item?id=1#reviews",
+			expected: "https://example.com/item?id",
+		},
+		{
+			name:     "Empty query",
+			input:    "https://example.com/item?",
+			expected: "https://example.com/item",
+		},
+		{
+			name:     "No query",
+			input:    "https://example.com/item#top",
+			expected: "https://example.com/item",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, paramsKey(tt.input))
+		})
+	}
+}
+
+func TestRepresentativeSet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Representative
+		wantErr  bool
+	}{
+		{input: "first", expected: KeepFirst},
+		{input: "last", expected: KeepLast},
+		{input: "shortest", expected: KeepShortest},
+		{input: "Longest", expected: KeepLongest},
+		{input: "random", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var keep Representative
+			err := keep.Set(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, keep)
+		})
+	}
+}
+
+func TestCleanDedupeParams(t *testing.T) {
+	input := []string{
+		"https://example.com/item?id=10",
+		"https://example.com/item?id=2",
+		"https://example.com/search?q=shoes&page=2",
+		"https://example.com/item?id=300&ref=home",
+		"https://example.com/search?page=1&q=hats",
+		"http://example.com/item?id=4",
+		"https://example.com/item?id=5",
+	}
+
+	tests := []struct {
+		name     string
+		keep     Representative
+		expected []string
+	}{
+		{
+			name:     "Keep first",
+			keep:     KeepFirst,
+			expected: []string{"https://example.com/item?id=10", "https://example.com/search?q=shoes&page=2", "https://example.com/item?id=300&ref=home"},
+		},
+		{
+			name:     "Keep last",
+			keep:     KeepLast,
+			expected: []string{"https://example.com/item?id=5", "https://example.com/search?page=1&q=hats", "https://example.com/item?id=300&ref=home"},
+		},
+		{
+			name:     "Keep shortest",
+			keep:     KeepShortest,
+			expected: []string{"https://example.com/item?id=2", "https://example.com/search?page=1&q=hats", "https://example.com/item?id=300&ref=home"},
+		},
+		{
+			name:     "Keep longest",
+			keep:     KeepLongest,
+			expected: []string{"https://example.com/item?id=10", "https://example.com/search?q=shoes&page=2", "https://example.com/item?id=300&ref=home"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.DedupeParams = true
+			opts.Keep = tt.keep
+			assert.Equal(t, tt.expected, New(opts).Clean(input))
+		})
+	}
+
+	// Without DedupeParams every URL is kept
+	assert.Len(t, New(DefaultOptions()).Clean(input), 7)
+}
+
+func TestStreamDedupeParams(t *testing.T) {
+	opts := DefaultOptions()
+	opts.DedupeParams = true
+	opts.Keep = KeepLast
+	s := New(opts).NewStream()
+
+	var result []string
+	for _, url := range []string{"https://example.com/item?id=1", "https://example.com/item?id=2", "https://example.com/item?id=3&x=1"} {
+		if cleaned, ok := s.Push(url); ok {
+			result = append(result, cleaned)
+		}
+	}
+	result = append(result, s.Flush()...)
+
+	// Streams always keep the first URL
+	assert.Equal(t, []string{"https://example.com/item?id=1", "https://example.com/item?id=3&x=1"}, result)
+}
@@ -0,0 +1,97 @@
+package cleanurl
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Placeholders that CollapsePaths puts in place of variable path segments.
+const (
+	PlaceholderInt  = "{int}"
+	PlaceholderUUID = "{uuid}"
+	PlaceholderHash = "{hash}"
+	PlaceholderDate = "{date}"
+	PlaceholderSlug = "{slug}"
+)
+
+var (
+	intSegment  = regexp.MustCompile(`^[0-9]+$`)
+	uuidSegment = regexp.MustCompile(`^(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+	hashSegment = regexp.MustCompile(`^(?i)[0-9a-f]{16,}$`)
+	dateSegment = regexp.MustCompile(`^[0-9]{4}-(0[1-9]|1[0-2])-(0[1-9]|[12][0-9]|3[01])$`)
+	// Slugs are at least four lowercase words, such as how-to-cook-pasta, so
+	// that names like user-profile are left alone
+	slugSegment = regexp.MustCompile(`^[a-z0-9]+(?:[-_][a-z0-9]+){3,}$`)
+)
+
+// collapsePath replaces the variable segments of the path of url with
+// placeholders, so that /users/123/orders/456 becomes
+// /users/{int}/orders/{int}. A file extension is kept, as in {int}.json.
+func collapsePath(url string) string {
+	start, end, ok := pathBounds(url)
+	if !ok {
+		return url
+	}
+
+	segments := strings.Split(url[start:end], "/")
+	for i, segment := range segments {
+		segments[i] = collapseSegment(segment)
+	}
+	return url[:start] + strings.Join(segments, "/") + url[end:]
+}
+
+// pathBounds returns where the path of url starts and ends, after the
+// authority and before the query or fragment. ok is false when url has no
+// path.
+func pathBounds(url string) (start, end int, ok bool) {
+	if idx := strings.Index(url, "://"); idx != -1 {
+		start = idx + len("://")
+	}
+	slash := strings.IndexAny(url[start:], "/?#")
+	if slash == -1 || url[start+slash] != '/' {
+		return 0, 0, false
+	}
+	start += slash
+
+	end = len(url)
+	if idx := strings.IndexAny(url[start:], "?#"); idx != -1 {
+		end = start + idx
+	}
+	return start, end, true
+}
+
+// collapseSegment returns the placeholder for a variable path segment, or
+// the segment unchanged.
+func collapseSegment(segment string) string {
+	name, ext := segment, ""
+	if idx := strings.IndexByte(segment, '.'); idx > 0 {
+		name, ext = segment[:idx], segment[idx:]
+	}
+
+	switch {
+	case intSegment.MatchString(name):
+		return PlaceholderInt + ext
+	case uuidSegment.MatchString(name):
+		return PlaceholderUUID + ext
+	case dateSegment.MatchString(name):
+		return PlaceholderDate + ext
+	case hashSegment.MatchString(name):
+		return PlaceholderHash + ext
+	case slugSegment.MatchString(name):
+		return PlaceholderSlug + ext
+	}
+	return segment
+}
+
+// pattern reduces a cleaned URL to the form the dedupe modes compare it by:
+// DedupeParams keeps only the parameter names and CollapsePaths puts
+// placeholders in the path. Other URLs are returned unchanged.
+func (c *Cleaner) pattern(url string) string {
+	if c.opts.DedupeParams {
+		url = paramsKey(url)
+	}
+	if c.opts.CollapsePaths {
+		url = collapsePath(url)
+	}
+	return url
+}
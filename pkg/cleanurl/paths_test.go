@@ -0,0 +1,152 @@
+package cleanurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollapsePath(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Numeric IDs",
+			input:    "https://api.example.com/users/123/orders/456",
+			expected: "https://api.example.com/users/{int}/orders/{int}",
+		},
+		{
+			name:     "UUID",
+			input:    "https://example.com/files/3F2504E0-4F89-11D3-9A0C-0305E82C3301/download",
+			expected: "https://example.com/files/{uuid}/download",
+		},
+		{
+			name:     "Hex hash",
+			input:    "https://example.com/blob/d41d8cd98f00b204e9800998ecf8427e",
+			expected: "https://example.com/blob/{hash}",
+		},
+		{
+			name:     "Date",
+			input:    "https://example.com/archive/2023-02-16/",
+			expected: "https://example.com/archive/{date}/",
+		},
+		{
+			name:     "Slug",
+			input:    "https://example.com/blog/how-to-cook-pasta",
+			expected: "https://example.com/blog/{slug}",
+		},
+		{
+			name:     "Short names are kept",
+			input:    "https://example.com/user-profile/settings/v2",
+			expected: "https://example.com/user-profile/settings/v2",
+		},
+		{
+			name:     "Extension is kept",
+			input:    "https://example.com/invoices/42.pdf",
+			expected: "https://example.com/invoices/{int}.pdf",
+		},
+		{
+			name:     "Query and fragment are untouched",
+			input:    "https://example.com/items/7?page=2&id=9#reviews/3",
+			expected: "https://example.com/items/{int}?page=2&id=9#reviews/3",
+		},
+		{
+			name:     "Numeric port is not a segment",
+			input:    "http://example.com:8080/items/7",
+			expected: "http://example.com:8080/items/{int}",
+		},
+		{
+			name:     "Schemeless URL",
+			input:    "example.com/items/7",
+			expected: "example.com/items/{int}",
+		},
+		{
+			name:     "No path",
+			input:    "https://example.com?id=1/2",
+			expected: "https://example.com?id=1/2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, collapsePath(tt.input))
+		})
+	}
+}
+
+func TestCleanCollapsePaths(t *testing.T) {
+	input := []string{
+		"https://api.example.com/users/123/orders/456",
+		"https://api.example.com/users/7/orders/8",
+		"https://api.example.com/users/7/profile",
+		"https://api.example.com/users/9/orders/10?expand=items",
+		"https://api.example.com/users/9/orders/11?expand=all",
+	}
+
+	tests := []struct {
+		name     string
+		opts     func(*Options)
+		expected []string
+	}{
+		{
+			name: "Sample URLs",
+			opts: func(o *Options) {},
+			expected: []string{
+				"https://api.example.com/users/123/orders/456",
+				"https://api.example.com/users/7/profile",
+				"https://api.example.com/users/9/orders/10?expand=items",
+				"https://api.example.com/users/9/orders/11?expand=all",
+			},
+		},
+		{
+			name: "Patterns",
+			opts: func(o *Options) { o.Patterns = true },
+			expected: []string{
+				"https://api.example.com/users/{int}/orders/{int}",
+				"https://api.example.com/users/{int}/profile",
+				"https://api.example.com/users/{int}/orders/{int}?expand=items",
+				"https://api.example.com/users/{int}/orders/{int}?expand=all",
+			},
+		},
+		{
+			name: "Patterns with parameter names",
+			opts: func(o *Options) { o.Patterns, o.DedupeParams = true, true },
+			expected: []string{
+				"https://api.example.com/users/{int}/orders/{int}",
+				"https://api.example.com/users/{int}/profile",
+				"https://api.example.com/users/{int}/orders/{int}?expand",
+			},
+		},
+		{
+			name: "Keep shortest",
+			opts: func(o *Options) { o.Keep = KeepShortest },
+			expected: []string{
+				"https://api.example.com/users/7/orders/8",
+				"https://api.example.com/users/7/profile",
+				"https://api.example.com/users/9/orders/10?expand=items",
+				"https://api.example.com/users/9/orders/11?expand=all",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			opts.CollapsePaths = true
+			tt.opts(&opts)
+			assert.Equal(t, tt.expected, New(opts).Clean(input))
+		})
+	}
+
+	opts := DefaultOptions()
+	opts.CollapsePaths = true
+	opts.Patterns = true
+	s := New(opts).NewStream()
+	first, ok := s.Push("https://api.example.com/users/123")
+	assert.True(t, ok)
+	assert.Equal(t, "https://api.example.com/users/{int}", first)
+	_, ok = s.Push("https://api.example.com/users/456")
+	assert.False(t, ok)
+}
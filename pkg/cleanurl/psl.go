@@ -0,0 +1,149 @@
+package cleanurl
+
+import (
+	"bufio"
+	_ "embed"
+	"net/netip"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+)
+
+//go:generate curl -sSfL -o public_suffix_list.dat https://publicsuffix.org/list/public_suffix_list.dat
+
+//go:embed public_suffix_list.dat
+var publicSuffixListData string
+
+// pslRule flags the kinds of Public Suffix List rules registered for a name.
+type pslRule uint8
+
+const (
+	pslNormal    pslRule = 1 << iota // "example"
+	pslWildcard                      // "*.example", stored under "example"
+	pslException                     // "!www.example", stored under "www.example"
+)
+
+// publicSuffixList holds the rules of the ICANN and private sections
+// separately, so that private suffixes can be ignored on request.
+type publicSuffixList struct {
+	icann   map[string]pslRule
+	private map[string]pslRule
+}
+
+var (
+	pslOnce    sync.Once
+	defaultPSL *publicSuffixList
+)
+
+// loadPublicSuffixList parses the embedded snapshot on first use.
+func loadPublicSuffixList() *publicSuffixList {
+	pslOnce.Do(func() {
+		defaultPSL = parsePublicSuffixList(publicSuffixListData)
+	})
+	return defaultPSL
+}
+
+func parsePublicSuffixList(data string) *publicSuffixList {
+	psl := &publicSuffixList{
+		icann:   make(map[string]pslRule),
+		private: make(map[string]pslRule),
+	}
+	section := psl.icann
+
+	scanner := bufio.NewScanner(strings.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case strings.HasPrefix(line, "// ===BEGIN PRIVATE DOMAINS==="):
+			section = psl.private
+			continue
+		case line == "" || strings.HasPrefix(line, "//"):
+			continue
+		}
+
+		// Rules end at the first whitespace
+		if idx := strings.IndexAny(line, " \t"); idx != -1 {
+			line = line[:idx]
+		}
+		line = strings.ToLower(line)
+
+		switch {
+		case strings.HasPrefix(line, "!"):
+			section[pslName(line[1:])] |= pslException
+		case strings.HasPrefix(line, "*."):
+			section[pslName(line[2:])] |= pslWildcard
+		default:
+			section[pslName(line)] |= pslNormal
+		}
+	}
+	return psl
+}
+
+// pslName returns a rule or host name in the ASCII form they are matched in:
+// the upstream list spells internationalized rules in Unicode, as in 公司.cn,
+// and URL hosts may carry them either way, as in xn--55qx5d.cn.
+func pslName(name string) string {
+	for i := 0; i < len(name); i++ {
+		if name[i] >= 0x80 {
+			if ascii, err := idna.ToASCII(name); err == nil {
+				return ascii
+			}
+			break
+		}
+	}
+	return name
+}
+
+// rule returns the flags registered for name, ignoring the private section
+// when icannOnly is set.
+func (psl *publicSuffixList) rule(name string, icannOnly bool) pslRule {
+	r := psl.icann[name]
+	if !icannOnly {
+		r |= psl.private[name]
+	}
+	return r
+}
+
+// publicSuffix returns the public suffix of host following the Public Suffix
+// List algorithm, falling back to the last label when no rule matches.
+func (psl *publicSuffixList) publicSuffix(host string, icannOnly bool) string {
+	labels := strings.Split(host, ".")
+	for i := range labels {
+		name := strings.Join(labels[i:], ".")
+		if psl.rule(name, icannOnly)&pslException != 0 {
+			return strings.Join(labels[i+1:], ".")
+		}
+		if psl.rule(name, icannOnly)&pslNormal != 0 {
+			return name
+		}
+		if i+1 < len(labels) && psl.rule(strings.Join(labels[i+1:], "."), icannOnly)&pslWildcard != 0 {
+			return name
+		}
+	}
+	return labels[len(labels)-1]
+}
+
+// rootDomain returns the registrable domain (eTLD+1) of host, such as
+// example.co.uk for a.b.example.co.uk. IP addresses, single-label hosts and
+// hosts that are themselves public suffixes are returned unchanged. With
+// icannOnly set, private-section suffixes such as github.io are not treated
+// as public suffixes, so they become roots themselves.
+func rootDomain(host string, icannOnly bool) string {
+	host = strings.TrimSuffix(host, ".")
+	if host == "" || strings.HasPrefix(host, "[") || !strings.Contains(host, ".") {
+		return host
+	}
+	if _, err := netip.ParseAddr(host); err == nil {
+		return host
+	}
+
+	// Match in punycode but keep the spelling of host, label for label
+	suffix := loadPublicSuffixList().publicSuffix(pslName(host), icannOnly)
+	labels := strings.Split(host, ".")
+	n := strings.Count(suffix, ".") + 1
+	if n >= len(labels) {
+		return host
+	}
+	return strings.Join(labels[len(labels)-n-1:], ".")
+}
@@ -0,0 +1,79 @@
+package cleanurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRootDomain(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		icannOnly bool
+		expected  string
+	}{
+		{name: "Simple domain", input: "example.com", expected: "example.com"},
+		{name: "Subdomain", input: "api.example.com", expected: "example.com"},
+		{name: "Multi-label suffix", input: "a.b.example.co.uk", expected: "example.co.uk"},
+		{name: "Private suffix", input: "user.github.io", expected: "user.github.io"},
+		{name: "Private suffix subdomain", input: "docs.user.github.io", expected: "user.github.io"},
+		{name: "Private suffix as root", input: "docs.user.github.io", icannOnly: true, expected: "github.io"},
+		{name: "Wildcard rule", input: "a.b.example.ck", expected: "b.example.ck"},
+		{name: "Exception rule", input: "a.www.ck", expected: "www.ck"},
+		{name: "Unicode host", input: "a.b.公司.cn", expected: "b.公司.cn"},
+		{name: "Punycode host", input: "a.b.xn--55qx5d.cn", expected: "b.xn--55qx5d.cn"},
+		{name: "Unicode public suffix", input: "公司.cn", expected: "公司.cn"},
+		{name: "Unknown TLD uses default rule", input: "a.example.unknowntld", expected: "example.unknowntld"},
+		{name: "Host is a public suffix", input: "co.uk", expected: "co.uk"},
+		{name: "Single label", input: "localhost", expected: "localhost"},
+		{name: "IPv4 address", input: "192.168.1.1", expected: "192.168.1.1"},
+		{name: "IPv6 literal", input: "[2001:db8::1]", expected: "[2001:db8::1]"},
+		{name: "Empty input", input: "", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := rootDomain(tt.input, tt.icannOnly)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParsePublicSuffixList(t *testing.T) {
+	psl := parsePublicSuffixList(`// comment
+// ===BEGIN ICANN DOMAINS===
+com
+*.ck
+!www.ck
+公司.cn
+*.РФ
+// ===END ICANN DOMAINS===
+// ===BEGIN PRIVATE DOMAINS===
+github.io extra text
+// ===END PRIVATE DOMAINS===
+`)
+
+	assert.Equal(t, pslNormal, psl.rule("com", true))
+	assert.Equal(t, pslWildcard, psl.rule("ck", true))
+	assert.Equal(t, pslException, psl.rule("www.ck", true))
+	assert.Equal(t, pslNormal, psl.rule("xn--55qx5d.cn", true))
+	assert.Equal(t, pslWildcard, psl.rule("xn--p1ai", true))
+	assert.Equal(t, pslRule(0), psl.rule("github.io", true))
+	assert.Equal(t, pslNormal, psl.rule("github.io", false))
+}
+
+func TestDomainsWithRootDomains(t *testing.T) {
+	input := []string{
+		"https://a.b.example.co.uk/path",
+		"http://www.example.co.uk:8080/",
+		"https://alice.github.io/repo",
+		"https://bob.github.io/",
+	}
+
+	cleaner := New(Options{RootDomains: true})
+	assert.Equal(t, []string{"example.co.uk", "alice.github.io", "bob.github.io"}, cleaner.Domains(input))
+
+	cleaner = New(Options{RootDomains: true, PrivateSuffixRoots: true})
+	assert.Equal(t, []string{"example.co.uk", "github.io"}, cleaner.Domains(input))
+}
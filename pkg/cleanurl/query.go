@@ -0,0 +1,88 @@
+package cleanurl
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// splitQuery splits raw around its query, which does not include the "?".
+// ok is false when raw has no query.
+func splitQuery(raw string) (before, query, after string, ok bool) {
+	start := strings.IndexByte(raw, '?')
+	if start == -1 {
+		return raw, "", "", false
+	}
+	end := len(raw)
+	if idx := strings.IndexByte(raw, '#'); idx != -1 {
+		if idx < start {
+			return raw, "", "", false
+		}
+		end = idx
+	}
+	return raw[:start], raw[start+1 : end], raw[end:], true
+}
+
+// paramName returns the decoded name of an encoded query parameter.
+func paramName(param string) string {
+	name, _, _ := strings.Cut(param, "=")
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
+
+// canonicalQuery is the query step: it rewrites the query of raw as selected
+// by SortQuery, DropEmptyParams, PercentSpaces and DropEmptyQuery, keeping
+// the encoding of the parameters otherwise.
+func (c *Cleaner) canonicalQuery(raw string) string {
+	before, query, after, ok := splitQuery(raw)
+	if !ok {
+		return raw
+	}
+
+	var params []string
+	if query != "" {
+		params = strings.Split(query, "&")
+	}
+
+	if c.opts.DropEmptyParams {
+		kept := params[:0:0]
+		for _, param := range params {
+			if param != "" && !strings.HasSuffix(param, "=") {
+				kept = append(kept, param)
+			}
+		}
+		params = kept
+	}
+	if c.opts.PercentSpaces {
+		for i, param := range params {
+			params[i] = strings.ReplaceAll(param, "+", "%20")
+		}
+	}
+	if c.opts.SortQuery {
+		// Stable, so that repeated keys keep their relative order
+		sort.SliceStable(params, func(i, j int) bool {
+			return paramName(params[i]) < paramName(params[j])
+		})
+	}
+
+	if len(params) == 0 && c.opts.DropEmptyQuery {
+		return before + after
+	}
+	return before + "?" + strings.Join(params, "&") + after
+}
+
+// canonicalQueries is the query step over a list of URLs.
+func (c *Cleaner) canonicalQueries(urls []string) []string {
+	result := make([]string, 0, len(urls))
+	for _, url := range urls {
+		result = append(result, c.canonicalQuery(url))
+	}
+	return result
+}
+
+// rewritesQuery reports whether any of the query options is set.
+func (c *Cleaner) rewritesQuery() bool {
+	return c.opts.SortQuery || c.opts.DropEmptyParams || c.opts.DropEmptyQuery || c.opts.PercentSpaces
+}
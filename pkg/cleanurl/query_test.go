@@ -0,0 +1,103 @@
+package cleanurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanonicalQuery(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		input    string
+		expected string
+	}{
+		{
+			name:     "Sort by name",
+			opts:     Options{SortQuery: true},
+			input:    "https://x.com/?b=2&a=1",
+			expected: "https://x.com/?a=1&b=2",
+		},
+		{
+			name:     "Repeated names keep their order",
+			opts:     Options{SortQuery: true},
+			input:    "https://x.com/?tag=z&id=1&tag=a",
+			expected: "https://x.com/?id=1&tag=z&tag=a",
+		},
+		{
+			name:     "Sort by decoded name",
+			opts:     Options{SortQuery: true},
+			input:    "https://x.com/?b=2&%61=1#frag",
+			expected: "https://x.com/?%61=1&b=2#frag",
+		},
+		{
+			name:     "Drop empty-valued parameters",
+			opts:     Options{DropEmptyParams: true},
+			input:    "https://x.com/?a=&b=2&&debug",
+			expected: "https://x.com/?b=2&debug",
+		},
+		{
+			name:     "Dropping every parameter keeps the question mark",
+			opts:     Options{DropEmptyParams: true},
+			input:    "https://x.com/?a=&b=",
+			expected: "https://x.com/?",
+		},
+		{
+			name:     "Drop empty query",
+			opts:     Options{DropEmptyParams: true, DropEmptyQuery: true},
+			input:    "https://x.com/?a=&b=",
+			expected: "https://x.com/",
+		},
+		{
+			name:     "Drop trailing question mark before a fragment",
+			opts:     Options{DropEmptyQuery: true},
+			input:    "https://x.com/a?#top",
+			expected: "https://x.com/a#top",
+		},
+		{
+			name:     "Percent-encoded spaces",
+			opts:     Options{PercentSpaces: true},
+			input:    "https://x.com/a+b?q=hello+world&r=a%20b",
+			expected: "https://x.com/a+b?q=hello%20world&r=a%20b",
+		},
+		{
+			name:     "No query",
+			opts:     Options{SortQuery: true, DropEmptyQuery: true},
+			input:    "https://x.com/a#?b=2&a=1",
+			expected: "https://x.com/a#?b=2&a=1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, New(tt.opts).canonicalQuery(tt.input))
+		})
+	}
+}
+
+func TestCleanCanonicalQuery(t *testing.T) {
+	input := []string{
+		"https://x.com/?b=2&a=1",
+		"https://x.com/?a=1&b=2",
+		"https://x.com/?a=1&b=2&c=",
+		"https://x.com/search?q=a+b",
+		"https://x.com/search?q=a%20b",
+		"https://x.com/page?",
+		"https://x.com/page",
+	}
+
+	// Without query options every spelling is kept
+	assert.Len(t, New(DefaultOptions()).Clean(input), 7)
+
+	opts := DefaultOptions()
+	opts.SortQuery = true
+	opts.DropEmptyParams = true
+	opts.DropEmptyQuery = true
+	opts.PercentSpaces = true
+	cleaner := New(opts)
+
+	assert.Equal(t, []string{"https://x.com/?a=1&b=2", "https://x.com/search?q=a%20b", "https://x.com/page"}, cleaner.Clean(input))
+	assert.Equal(t, "https://x.com/?a=1&b=2", cleaner.CleanOne("https://x.com/?b=2&c=&a=1"))
+	assert.Equal(t, []string{RuleQuery}, cleaner.CleanResults(input)[0].Rules)
+}
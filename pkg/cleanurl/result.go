@@ -0,0 +1,90 @@
+package cleanurl
+
+import (
+	"net/url"
+	"strings"
+)
+
+// Names of the cleaning rules reported in Result.Rules.
+const (
+	RuleLower         = "lower"
+	RuleCharacters    = "characters"
+	RuleStripTracking = "strip-tracking"
+	RuleQuery         = "query"
+	RuleNormalize     = "normalize"
+	RuleBackslash     = "backslash"
+	RuleCleanHTTP     = "clean-http"
+)
+
+// Components are the parts of a parsed URL.
+type Components struct {
+	Scheme   string              `json:"scheme"`
+	Host     string              `json:"host"`
+	Port     string              `json:"port"`
+	Path     string              `json:"path"`
+	Query    map[string][]string `json:"query"`
+	Fragment string              `json:"fragment"`
+	// RawQuery is the encoded query as it appears in the URL, without "?".
+	RawQuery string `json:"-"`
+}
+
+// Result describes one URL in the output of CleanResults.
+type Result struct {
+	// Original is the input line the URL was first seen as.
+	Original string `json:"original"`
+	// URL is the cleaned URL, as returned by Clean.
+	URL string `json:"url"`
+	Components
+	// Rules lists the cleaning rules that changed the URL. RuleCleanHTTP is
+	// reported on HTTPS URLs that replaced an HTTP duplicate.
+	Rules []string `json:"rules"`
+}
+
+// CleanResults is Clean with the original input, the parsed components of
+// the cleaned URL and the cleaning rules that fired for every output URL.
+func (c *Cleaner) CleanResults(urls []string) []Result {
+	results := c.clean(urls)
+	for i := range results {
+		results[i].complete()
+	}
+	return results
+}
+
+// complete fills the parsed components and makes Rules non-nil.
+func (r *Result) complete() {
+	r.Components = ParseComponents(r.URL)
+	if r.Rules == nil {
+		r.Rules = []string{}
+	}
+}
+
+// ParseComponents splits raw into its components. A URL without a scheme,
+// such as example.com/path, is parsed as if it started with "//" so that its
+// host is still recognised. Query is never nil.
+func ParseComponents(raw string) Components {
+	u, err := parseLenient(raw)
+	if err != nil {
+		return Components{Query: map[string][]string{}}
+	}
+	return Components{
+		Scheme:   u.Scheme,
+		Host:     u.Hostname(),
+		Port:     u.Port(),
+		Path:     u.Path,
+		Query:    u.Query(),
+		Fragment: u.Fragment,
+		RawQuery: u.RawQuery,
+	}
+}
+
+// parseLenient parses raw with net/url, retrying schemeless input such as
+// example.com:8080/path as a network-path reference.
+func parseLenient(raw string) (*url.URL, error) {
+	if strings.Contains(raw, "://") || strings.HasPrefix(raw, "//") {
+		return url.Parse(raw)
+	}
+	if u, err := url.Parse("//" + raw); err == nil {
+		return u, nil
+	}
+	return url.Parse(raw)
+}
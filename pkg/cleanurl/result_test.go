@@ -0,0 +1,127 @@
+package cleanurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCleanResults(t *testing.T) {
+	input := []string{`"HTTPS://Example.com/Path/?a=1&b=x%20y#top"`, "http://example.com/Path?a=1&b=x%20y#top", "https://test.com"}
+
+	results := New(DefaultOptions()).CleanResults(input)
+
+	assert.Equal(t, []Result{
+		{
+			Original: `"HTTPS://Example.com/Path/?a=1&b=x%20y#top"`,
+			URL:      "https://example.com/Path/?a=1&b=x%20y#top",
+			Components: Components{
+				Scheme:   "https",
+				Host:     "example.com",
+				Path:     "/Path/",
+				Query:    map[string][]string{"a": {"1"}, "b": {"x y"}},
+				Fragment: "top",
+				RawQuery: "a=1&b=x%20y",
+			},
+			Rules: []string{RuleLower, RuleCharacters},
+		},
+		{
+			Original: "http://example.com/Path?a=1&b=x%20y#top",
+			URL:      "http://example.com/Path?a=1&b=x%20y#top",
+			Components: Components{
+				Scheme:   "http",
+				Host:     "example.com",
+				Path:     "/Path",
+				Query:    map[string][]string{"a": {"1"}, "b": {"x y"}},
+				Fragment: "top",
+				RawQuery: "a=1&b=x%20y",
+			},
+			Rules: []string{},
+		},
+		{
+			Original:   "https://test.com",
+			URL:        "https://test.com",
+			Components: Components{Scheme: "https", Host: "test.com", Query: map[string][]string{}},
+			Rules:      []string{},
+		},
+	}, results)
+}
+
+func TestCleanResultsRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		opts     Options
+		expected [][]string
+	}{
+		{
+			name:     "Backslash and lowercase",
+			input:    []string{"HTTPS://EXAMPLE.COM/"},
+			opts:     DefaultOptions(),
+			expected: [][]string{{RuleLower, RuleBackslash}},
+		},
+		{
+			name:     "HTTPS replaces HTTP duplicate",
+			input:    []string{"http://example.com/a", "https://example.com/a/"},
+			opts:     DefaultOptions(),
+			expected: [][]string{{RuleBackslash, RuleCleanHTTP}},
+		},
+		{
+			name:     "Normalization",
+			input:    []string{"https://example.com:443/a/../b"},
+			opts:     Options{Normalize: true},
+			expected: [][]string{{RuleNormalize}},
+		},
+		{
+			name:     "Nothing changed",
+			input:    []string{"https://example.com/a"},
+			opts:     DefaultOptions(),
+			expected: [][]string{{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules [][]string
+			for _, r := range New(tt.opts).CleanResults(tt.input) {
+				rules = append(rules, r.Rules)
+			}
+			assert.Equal(t, tt.expected, rules)
+		})
+	}
+}
+
+func TestParseComponents(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Components
+	}{
+		{
+			name:     "Full URL",
+			input:    "https://user@example.com:8080/a/b?x=1&x=2#frag",
+			expected: Components{Scheme: "https", Host: "example.com", Port: "8080", Path: "/a/b", Query: map[string][]string{"x": {"1", "2"}}, Fragment: "frag", RawQuery: "x=1&x=2"},
+		},
+		{
+			name:     "IPv6 literal",
+			input:    "http://[2001:db8::1]:8080/",
+			expected: Components{Scheme: "http", Host: "2001:db8::1", Port: "8080", Path: "/", Query: map[string][]string{}},
+		},
+		{
+			name:     "No scheme",
+			input:    "example.com:8080/path",
+			expected: Components{Host: "example.com", Port: "8080", Path: "/path", Query: map[string][]string{}},
+		},
+		{
+			name:     "Unparsable input",
+			input:    "http://exa mple.com/",
+			expected: Components{Query: map[string][]string{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, ParseComponents(tt.input))
+		})
+	}
+}
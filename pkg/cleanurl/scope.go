@@ -0,0 +1,166 @@
+package cleanurl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/netip"
+	"strings"
+)
+
+// Scope is a set of hosts, such as the scope of a bug bounty program.
+//
+// Rules are read one per line: an exact host (example.com), a wildcard that
+// matches a domain and all its subdomains (*.example.com), an IP address or
+// CIDR range (10.0.0.0/8, 2001:db8::/32), each optionally followed by a path
+// prefix (example.com/api). A leading scheme is ignored. Blank lines and
+// lines starting with "#" are skipped.
+type Scope struct {
+	rules []scopeRule
+}
+
+type scopeRule struct {
+	host     string // exact host, or the domain of a wildcard
+	wildcard bool
+	prefix   netip.Prefix
+	path     string
+}
+
+// ParseScope reads scope rules from r.
+func ParseScope(r io.Reader) (*Scope, error) {
+	s := &Scope{}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		rule, err := parseScopeRule(line)
+		if err != nil {
+			return nil, fmt.Errorf("scope line %d: %w", n, err)
+		}
+		s.rules = append(s.rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func parseScopeRule(line string) (scopeRule, error) {
+	var rule scopeRule
+
+	if idx := strings.Index(line, "://"); idx != -1 {
+		line = line[idx+len("://"):]
+	}
+	host, path, _ := strings.Cut(line, "/")
+	host = strings.ToLower(host)
+
+	// A CIDR range has a slash of its own before the path
+	if bits, rest, _ := strings.Cut(path, "/"); bits != "" {
+		if prefix, err := netip.ParsePrefix(strings.Trim(host, "[]") + "/" + bits); err == nil {
+			rule.prefix = prefix.Masked()
+			path = rest
+		}
+	}
+	rule.path = strings.TrimSuffix("/"+path, "/")
+	if rule.prefix.IsValid() {
+		return rule, nil
+	}
+
+	if addr, ok := parseScopeAddr(host); ok {
+		rule.prefix = netip.PrefixFrom(addr, addr.BitLen())
+		return rule, nil
+	}
+
+	if domain, ok := strings.CutPrefix(host, "*."); ok {
+		rule.wildcard = true
+		host = domain
+	}
+	host = strings.TrimPrefix(host, "www.")
+	if host == "" || strings.ContainsAny(host, "*:") {
+		return rule, fmt.Errorf("invalid host %q", host)
+	}
+	rule.host = host
+	return rule, nil
+}
+
+// parseScopeAddr parses an IP address host, bracketed or not, with any zone
+// dropped and IPv4-mapped IPv6 addresses unmapped.
+func parseScopeAddr(host string) (netip.Addr, bool) {
+	host = strings.Trim(host, "[]")
+	if idx := strings.IndexByte(host, '%'); idx != -1 {
+		host = host[:idx]
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// Contains reports whether the host of url, parsed as in scopeHost, and its
+// path match any rule of s.
+func (s *Scope) Contains(url string) bool {
+	host := scopeHost(url)
+	addr, isAddr := parseScopeAddr(host)
+
+	path := "/"
+	if start, end, ok := pathBounds(url); ok {
+		path = url[start:end]
+	}
+
+	for _, rule := range s.rules {
+		switch {
+		case rule.prefix.IsValid():
+			if !isAddr || !rule.prefix.Contains(addr) {
+				continue
+			}
+		case rule.wildcard:
+			if host != rule.host && !strings.HasSuffix(host, "."+rule.host) {
+				continue
+			}
+		case host != rule.host:
+			continue
+		}
+		if rule.path == "" || path == rule.path || strings.HasPrefix(path, rule.path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// scopeHost returns the host of url as compared to scope rules: the
+// authority cut at the first of "/?#", without userinfo, then normalized as
+// in extractDomain. A query or fragment such as ?.example.com or userinfo
+// such as example.com@ can thus not pass for the host.
+func scopeHost(url string) string {
+	authority := strings.ToLower(url)
+	if idx := strings.Index(authority, "://"); idx != -1 {
+		authority = authority[idx+len("://"):]
+	}
+	if idx := strings.IndexAny(authority, "/?#"); idx != -1 {
+		authority = authority[:idx]
+	}
+	if idx := strings.LastIndexByte(authority, '@'); idx != -1 {
+		authority = authority[idx+1:]
+	}
+	return extractDomain(authority)
+}
+
+// inScope applies the Scope and OutOfScope options to a cleaned URL,
+// reporting URLs it drops to OnOutOfScope.
+func (c *Cleaner) inScope(url string) bool {
+	if c.opts.Scope == nil && c.opts.OutOfScope == nil {
+		return true
+	}
+	if (c.opts.Scope == nil || c.opts.Scope.Contains(url)) &&
+		(c.opts.OutOfScope == nil || !c.opts.OutOfScope.Contains(url)) {
+		return true
+	}
+	if c.opts.OnOutOfScope != nil {
+		c.opts.OnOutOfScope(url)
+	}
+	return false
+}
@@ -0,0 +1,191 @@
+package cleanurl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScopeContains(t *testing.T) {
+	scope, err := ParseScope(strings.NewReader(`# program scope
+example.com
+*.api.example.org
+https://shop.example.net/checkout/
+10.0.0.0/8
+192.168.1.10
+[2001:db8::]/32/admin
+`))
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		input    string
+		expected bool
+	}{
+		{
+			name:     "Exact host",
+			input:    "https://example.com/path",
+			expected: true,
+		},
+		{
+			name:     "www prefix is ignored as in extractDomain",
+			input:    "https://www.example.com/",
+			expected: true,
+		},
+		{
+			name:     "Exact host does not cover subdomains",
+			input:    "https://app.example.com/",
+			expected: false,
+		},
+		{
+			name:     "Wildcard subdomain",
+			input:    "https://v2.eu.api.example.org/users",
+			expected: true,
+		},
+		{
+			name:     "Wildcard covers the domain itself",
+			input:    "http://api.example.org:8080",
+			expected: true,
+		},
+		{
+			name:     "Wildcard needs a label boundary",
+			input:    "https://evilapi.example.org/",
+			expected: false,
+		},
+		{
+			name:     "Path prefix",
+			input:    "https://shop.example.net/checkout/cart?id=1",
+			expected: true,
+		},
+		{
+			name:     "Path prefix itself",
+			input:    "https://shop.example.net/checkout",
+			expected: true,
+		},
+		{
+			name:     "Path prefix needs a segment boundary",
+			input:    "https://shop.example.net/checkouts",
+			expected: false,
+		},
+		{
+			name:     "Outside the path prefix",
+			input:    "https://shop.example.net/",
+			expected: false,
+		},
+		{
+			name:     "IPv4 CIDR range",
+			input:    "http://10.20.30.40:8080/",
+			expected: true,
+		},
+		{
+			name:     "Single address",
+			input:    "192.168.1.10/login",
+			expected: true,
+		},
+		{
+			name:     "IPv4-mapped address",
+			input:    "http://[::ffff:10.1.2.3]/",
+			expected: true,
+		},
+		{
+			name:     "IPv6 CIDR range with path prefix",
+			input:    "https://[2001:db8:1::5]/admin/users",
+			expected: true,
+		},
+		{
+			name:     "IPv6 CIDR range outside the path prefix",
+			input:    "https://[2001:db8:1::5]/",
+			expected: false,
+		},
+		{
+			name:     "Userinfo does not fake the host",
+			input:    "https://example.com@evil.com/",
+			expected: false,
+		},
+		{
+			name:     "Userinfo is not the host",
+			input:    "https://user:pw@v2.api.example.org/x",
+			expected: true,
+		},
+		{
+			name:     "Query does not extend the host",
+			input:    "https://evil.com?.api.example.org",
+			expected: false,
+		},
+		{
+			name:     "Fragment does not extend the host",
+			input:    "https://evil.com#.api.example.org",
+			expected: false,
+		},
+		{
+			name:     "Other host",
+			input:    "https://example.co/",
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, scope.Contains(tt.input))
+		})
+	}
+}
+
+func TestParseScopeErrors(t *testing.T) {
+	for _, input := range []string{"example.com\n*.\n", "ex*ample.com", "example.com:8080"} {
+		_, err := ParseScope(strings.NewReader(input))
+		assert.Error(t, err, input)
+	}
+
+	_, err := ParseScope(strings.NewReader("example.com\n\n*.\n"))
+	assert.EqualError(t, err, `scope line 3: invalid host ""`)
+}
+
+func TestCleanScope(t *testing.T) {
+	scope, _ := ParseScope(strings.NewReader("*.example.com"))
+	outOfScope, _ := ParseScope(strings.NewReader("admin.example.com\nexample.com/private"))
+
+	var rejected []string
+	opts := DefaultOptions()
+	opts.Scope = scope
+	opts.OutOfScope = outOfScope
+	opts.OnOutOfScope = func(url string) { rejected = append(rejected, url) }
+	cleaner := New(opts)
+
+	input := []string{
+		"https://example.com/",
+		"https://admin.example.com/login",
+		"https://api.example.com/v1",
+		"https://other.com/",
+		"https://example.com/private/data",
+	}
+
+	assert.Equal(t, []string{"https://example.com", "https://api.example.com/v1"}, cleaner.Clean(input))
+	assert.Equal(t, []string{"https://admin.example.com/login", "https://other.com", "https://example.com/private/data"}, rejected)
+}
+
+func TestDomainsScope(t *testing.T) {
+	scope, _ := ParseScope(strings.NewReader("*.example.com"))
+
+	var rejected []string
+	opts := DefaultOptions()
+	opts.Scope = scope
+	opts.OnOutOfScope = func(url string) { rejected = append(rejected, url) }
+
+	input := []string{
+		"https://api.example.com/v1",
+		"https://other.com/",
+		"https://www.example.com/",
+		"https://cdn.other.com/a.js",
+	}
+
+	assert.Equal(t, []string{"api.example.com", "example.com"}, New(opts).Domains(input))
+	assert.Equal(t, []string{"api.example.com", "example.com"}, runStream(New(opts).NewDomainStream(), input))
+	assert.Equal(t, []SubdomainGroup{{Root: "example.com", Hosts: []string{"api.example.com"}}}, New(opts).Subdomains(input))
+	assert.Equal(t, []string{
+		"https://other.com", "https://cdn.other.com/a.js",
+		"https://other.com", "https://cdn.other.com/a.js",
+		"https://other.com", "https://cdn.other.com/a.js",
+	}, rejected)
+}
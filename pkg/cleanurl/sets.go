@@ -0,0 +1,98 @@
+package cleanurl
+
+import (
+	"strings"
+)
+
+// Comparison is the result of comparing two URL lists with Compare.
+type Comparison struct {
+	// Removed lists the URLs only in the first list, in its order.
+	Removed []string
+	// Common lists the URLs in both lists, in the order and form of the
+	// first list.
+	Common []string
+	// Added lists the URLs only in the second list, in its order.
+	Added []string
+}
+
+// Compare cleans a and b and compares them by the keys URLs are
+// deduplicated by. With CleanHTTP set, an HTTP URL and its HTTPS twin are the
+// same URL, even in different lists.
+func (c *Cleaner) Compare(a, b []string) Comparison {
+	urlsA, keysA := c.cleanSet(a)
+	urlsB, keysB := c.cleanSet(b)
+	inA, inB := keySet(keysA), keySet(keysB)
+
+	comparison := Comparison{Removed: []string{}, Common: []string{}, Added: []string{}}
+	for i, url := range urlsA {
+		if inB[keysA[i]] {
+			comparison.Common = append(comparison.Common, url)
+		} else {
+			comparison.Removed = append(comparison.Removed, url)
+		}
+	}
+	for i, url := range urlsB {
+		if !inA[keysB[i]] {
+			comparison.Added = append(comparison.Added, url)
+		}
+	}
+	return comparison
+}
+
+// Intersect cleans the lists and returns the URLs found in all of them, in
+// the order and form of the first. URLs are compared as in Compare.
+func (c *Cleaner) Intersect(lists ...[]string) []string {
+	if len(lists) == 0 {
+		return []string{}
+	}
+
+	urls, keys := c.cleanSet(lists[0])
+	for _, list := range lists[1:] {
+		_, otherKeys := c.cleanSet(list)
+		other := keySet(otherKeys)
+
+		kept, keptKeys := urls[:0], keys[:0]
+		for i, key := range keys {
+			if other[key] {
+				kept = append(kept, urls[i])
+				keptKeys = append(keptKeys, key)
+			}
+		}
+		urls, keys = kept, keptKeys
+	}
+	return urls
+}
+
+// Union cleans the lists and returns the URLs found in any of them, in order
+// of first occurrence, exactly as Clean does for the lists put end to end.
+func (c *Cleaner) Union(lists ...[]string) []string {
+	var all []string
+	for _, list := range lists {
+		all = append(all, list...)
+	}
+	return c.Clean(all)
+}
+
+// cleanSet cleans urls and returns the output URLs with the keys they are
+// compared to other lists by: their dedupe keys, with HTTP URLs keyed as
+// their HTTPS twin when CleanHTTP is set.
+func (c *Cleaner) cleanSet(urls []string) ([]string, []string) {
+	results, keys := c.cleanKeys(urls)
+	cleaned := make([]string, 0, len(results))
+	for i, r := range results {
+		cleaned = append(cleaned, r.URL)
+		if rest, ok := strings.CutPrefix(keys[i], "http://"); ok && c.opts.CleanHTTP {
+			keys[i] = "https://" + rest
+		}
+	}
+	return cleaned, keys
+}
+
+// keySet returns keys as a set.
+func keySet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
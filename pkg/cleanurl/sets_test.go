@@ -0,0 +1,102 @@
+package cleanurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		name     string
+		a        []string
+		b        []string
+		modify   func(*Options)
+		expected Comparison
+	}{
+		{
+			name: "Added, removed and common URLs",
+			a:    []string{"https://a.com/", "https://b.com", "https://c.com"},
+			b:    []string{"https://d.com", "https://c.com", "https://a.com"},
+			expected: Comparison{
+				Removed: []string{"https://b.com"},
+				Common:  []string{"https://a.com", "https://c.com"},
+				Added:   []string{"https://d.com"},
+			},
+		},
+		{
+			name: "HTTP and HTTPS twins are the same URL",
+			a:    []string{"http://a.com/x", "https://b.com"},
+			b:    []string{"https://a.com/x", "http://b.com/"},
+			expected: Comparison{
+				Removed: []string{},
+				Common:  []string{"http://a.com/x", "https://b.com"},
+				Added:   []string{},
+			},
+		},
+		{
+			name:   "HTTP and HTTPS differ without CleanHTTP",
+			a:      []string{"http://a.com"},
+			b:      []string{"https://a.com"},
+			modify: func(o *Options) { o.CleanHTTP = false },
+			expected: Comparison{
+				Removed: []string{"http://a.com"},
+				Common:  []string{},
+				Added:   []string{"https://a.com"},
+			},
+		},
+		{
+			name:   "Cleaning options apply before comparing",
+			a:      []string{"https://a.com/?utm_source=x&id=1"},
+			b:      []string{"https://A.com/?id=1&fbclid=y"},
+			modify: func(o *Options) { o.StripTracking = true },
+			expected: Comparison{
+				Removed: []string{},
+				Common:  []string{"https://a.com/?id=1"},
+				Added:   []string{},
+			},
+		},
+		{
+			name: "Empty lists",
+			expected: Comparison{
+				Removed: []string{},
+				Common:  []string{},
+				Added:   []string{},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := DefaultOptions()
+			if tt.modify != nil {
+				tt.modify(&opts)
+			}
+			assert.Equal(t, tt.expected, New(opts).Compare(tt.a, tt.b))
+		})
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	cleaner := New(DefaultOptions())
+
+	assert.Equal(t, []string{"http://a.com", "https://c.com"}, cleaner.Intersect(
+		[]string{"http://a.com", "https://b.com", "https://c.com/"},
+		[]string{"https://c.com", "https://a.com", "https://b.com"},
+		[]string{"https://a.com", "http://c.com", "https://d.com"},
+	))
+	assert.Equal(t, []string{"https://a.com"}, cleaner.Intersect([]string{"https://a.com/", "https://a.com"}))
+	assert.Equal(t, []string{}, cleaner.Intersect())
+	assert.Equal(t, []string{}, cleaner.Intersect([]string{"https://a.com"}, nil))
+}
+
+func TestUnion(t *testing.T) {
+	cleaner := New(DefaultOptions())
+
+	// The HTTPS twin replaces an HTTP URL at its own position, as in Clean
+	assert.Equal(t, []string{"https://b.com", "https://a.com", "https://c.com"}, cleaner.Union(
+		[]string{"http://a.com", "https://b.com/"},
+		[]string{"https://a.com", "https://c.com", "https://b.com"},
+	))
+	assert.Equal(t, []string{}, cleaner.Union())
+}
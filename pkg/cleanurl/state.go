@@ -0,0 +1,183 @@
+package cleanurl
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+)
+
+// stateMagic starts every SeenSet file and versions its format.
+var stateMagic = []byte("CLNSEEN1")
+
+// stateRecordSize is the size of a stored key: its 128-bit FNV-1a hash.
+const stateRecordSize = 16
+
+type stateRecord [stateRecordSize]byte
+
+// SeenSet is a persistent set of URL keys, used to output only the URLs not
+// seen in previous runs.
+//
+// The file holds a short header followed by a 16-byte hash per key, so it
+// stays compact however long the URLs are. Save appends the new keys in a
+// single write under an exclusive lock on Unix systems, so concurrent runs
+// neither interleave nor duplicate records. A write cut short by a crash
+// leaves a partial record, which is ignored when reading and overwritten by
+// the next Save.
+type SeenSet struct {
+	f       *os.File
+	name    string
+	seen    map[stateRecord]bool
+	pending []stateRecord
+	offset  int64 // end of the records read so far
+}
+
+// OpenSeenSet opens the set stored in the file name, creating it if needed.
+func OpenSeenSet(name string) (*SeenSet, error) {
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	s := &SeenSet{f: f, name: name, seen: make(map[stateRecord]bool)}
+
+	err = s.locked(func() error {
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if info.Size() == 0 {
+			if _, err := f.Write(stateMagic); err != nil {
+				return err
+			}
+			s.offset = int64(len(stateMagic))
+			return nil
+		}
+
+		header := make([]byte, len(stateMagic))
+		if _, err := io.ReadFull(f, header); err != nil || !bytes.Equal(header, stateMagic) {
+			return errors.New("not a cleanurl state file")
+		}
+		s.offset = int64(len(stateMagic))
+		return s.readNew(nil)
+	})
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return s, nil
+}
+
+// readNew reads the records appended after offset, by another run since
+// they were last read, leaving out a trailing partial record. It must be
+// called with the lock held. Keys read that are also in pending are
+// removed from it.
+func (s *SeenSet) readNew(pending map[stateRecord]bool) error {
+	info, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	end := s.offset + (info.Size()-s.offset)/stateRecordSize*stateRecordSize
+	if end <= s.offset {
+		return nil
+	}
+
+	data := make([]byte, end-s.offset)
+	if _, err := s.f.ReadAt(data, s.offset); err != nil {
+		return err
+	}
+	for i := 0; i < len(data); i += stateRecordSize {
+		var record stateRecord
+		copy(record[:], data[i:])
+		s.seen[record] = true
+		delete(pending, record)
+	}
+	s.offset = end
+	return nil
+}
+
+// Len returns the number of keys in the set, saved or not.
+func (s *SeenSet) Len() int {
+	return len(s.seen)
+}
+
+// Contains reports whether key is in the set.
+func (s *SeenSet) Contains(key string) bool {
+	return s.seen[hashKey(key)]
+}
+
+// Add adds key to the set and reports whether it was new. New keys are
+// stored by the next Save.
+func (s *SeenSet) Add(key string) bool {
+	record := hashKey(key)
+	if s.seen[record] {
+		return false
+	}
+	s.seen[record] = true
+	s.pending = append(s.pending, record)
+	return true
+}
+
+// Save appends the keys added since the last Save to the file and syncs it.
+func (s *SeenSet) Save() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	err := s.locked(func() error {
+		// Skip the keys another run saved in the meantime
+		pending := make(map[stateRecord]bool, len(s.pending))
+		for _, record := range s.pending {
+			pending[record] = true
+		}
+		if err := s.readNew(pending); err != nil {
+			return err
+		}
+
+		// Overwrite a partial record left by a crashed run
+		if err := s.f.Truncate(s.offset); err != nil {
+			return err
+		}
+
+		data := make([]byte, 0, len(pending)*stateRecordSize)
+		for _, record := range s.pending {
+			if pending[record] {
+				data = append(data, record[:]...)
+			}
+		}
+		if _, err := s.f.WriteAt(data, s.offset); err != nil {
+			return err
+		}
+		s.offset += int64(len(data))
+		return s.f.Sync()
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", s.name, err)
+	}
+	s.pending = nil
+	return nil
+}
+
+// Close closes the file without saving.
+func (s *SeenSet) Close() error {
+	return s.f.Close()
+}
+
+// locked runs fn while holding the lock on the file.
+func (s *SeenSet) locked(fn func() error) error {
+	if err := lockFile(s.f); err != nil {
+		return err
+	}
+	defer unlockFile(s.f)
+	return fn()
+}
+
+// hashKey returns the record stored for key.
+func hashKey(key string) stateRecord {
+	var record stateRecord
+	h := fnv.New128a()
+	h.Write([]byte(key))
+	h.Sum(record[:0])
+	return record
+}
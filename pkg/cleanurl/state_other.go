@@ -0,0 +1,15 @@
+//go:build !unix
+
+package cleanurl
+
+import "os"
+
+// lockFile is a no-op where flock is not available, so concurrent runs must
+// not share a state file there.
+func lockFile(f *os.File) error {
+	return nil
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,129 @@
+package cleanurl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSeenSet(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "seen.db")
+
+	s, err := OpenSeenSet(name)
+	assert.NoError(t, err)
+	assert.True(t, s.Add("https://a.com"))
+	assert.True(t, s.Add("https://b.com"))
+	assert.False(t, s.Add("https://a.com"))
+	assert.NoError(t, s.Save())
+	assert.NoError(t, s.Close())
+
+	info, err := os.Stat(name)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(stateMagic)+2*stateRecordSize), info.Size())
+
+	s, err = OpenSeenSet(name)
+	assert.NoError(t, err)
+	defer s.Close()
+	assert.Equal(t, 2, s.Len())
+	assert.True(t, s.Contains("https://a.com"))
+	assert.False(t, s.Contains("https://c.com"))
+}
+
+func TestSeenSetConcurrentSaves(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "seen.db")
+
+	first, err := OpenSeenSet(name)
+	assert.NoError(t, err)
+	defer first.Close()
+	second, err := OpenSeenSet(name)
+	assert.NoError(t, err)
+	defer second.Close()
+
+	first.Add("https://a.com")
+	first.Add("https://b.com")
+	second.Add("https://b.com")
+	second.Add("https://c.com")
+	assert.NoError(t, first.Save())
+	assert.NoError(t, second.Save())
+
+	// The key both runs added is stored once, and each run sees the other's
+	assert.Equal(t, 3, second.Len())
+	info, _ := os.Stat(name)
+	assert.Equal(t, int64(len(stateMagic)+3*stateRecordSize), info.Size())
+
+	reopened, err := OpenSeenSet(name)
+	assert.NoError(t, err)
+	defer reopened.Close()
+	assert.Equal(t, 3, reopened.Len())
+}
+
+func TestSeenSetPartialRecord(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "seen.db")
+
+	s, _ := OpenSeenSet(name)
+	s.Add("https://a.com")
+	s.Save()
+	s.Close()
+
+	// A crash in the middle of a save leaves part of a record
+	f, _ := os.OpenFile(name, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write([]byte{1, 2, 3})
+	f.Close()
+
+	s, err := OpenSeenSet(name)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, s.Len())
+	s.Add("https://b.com")
+	assert.NoError(t, s.Save())
+	s.Close()
+
+	s, err = OpenSeenSet(name)
+	assert.NoError(t, err)
+	defer s.Close()
+	assert.True(t, s.Contains("https://a.com"))
+	assert.True(t, s.Contains("https://b.com"))
+}
+
+func TestOpenSeenSetInvalid(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "urls.txt")
+	os.WriteFile(name, []byte("https://example.com\n"), 0o644)
+
+	_, err := OpenSeenSet(name)
+	assert.EqualError(t, err, name+": not a cleanurl state file")
+}
+
+func TestCleanSeen(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "seen.db")
+
+	run := func(input []string, stream bool) []string {
+		seen, err := OpenSeenSet(name)
+		assert.NoError(t, err)
+		defer seen.Close()
+
+		opts := DefaultOptions()
+		opts.Seen = seen
+		cleaner := New(opts)
+
+		var result []string
+		if stream {
+			s := cleaner.NewStream()
+			for _, url := range input {
+				if cleaned, ok := s.Push(url); ok {
+					result = append(result, cleaned)
+				}
+			}
+			result = append(result, s.Flush()...)
+		} else {
+			result = cleaner.Clean(input)
+		}
+		assert.NoError(t, seen.Save())
+		return result
+	}
+
+	assert.Equal(t, []string{"https://a.com", "http://b.com"}, run([]string{"https://a.com/", "http://b.com", "https://a.com"}, false))
+	assert.Equal(t, []string{"https://c.com"}, run([]string{"https://a.com", "https://c.com", "http://b.com/"}, false))
+	assert.Equal(t, []string{"http://d.com"}, run([]string{"https://c.com", "http://d.com", "http://b.com"}, true))
+	assert.Equal(t, []string{}, run([]string{"http://d.com", "https://a.com"}, false))
+}
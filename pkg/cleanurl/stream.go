@@ -0,0 +1,194 @@
+package cleanurl
+
+import (
+	"strings"
+)
+
+// Stream cleans and deduplicates URLs one at a time, keeping only the
+// deduplication state in memory.
+//
+// Every URL is emitted as soon as its fate is known. HTTP URLs are the
+// exception when CleanHTTP is set: an HTTPS twin may still follow, so they are
+// deferred and emitted by Flush only if no HTTPS twin was seen. The set of
+// emitted URLs matches Clean; only the position of deferred HTTP URLs differs.
+//
+// With ApproxDedupe, seen keys are kept in a BloomFilter instead, so memory
+// stays bounded but a URL may be dropped as the duplicate of one never seen.
+// HTTPS URLs are tracked in a second filter of the same size, and nothing is
+// deferred: an HTTP URL is emitted at once unless its HTTPS twin was already
+// seen, so both are emitted when the twin comes later.
+type Stream struct {
+	cleaner  *Cleaner
+	domains  bool
+	seen     dedupeSet
+	httpsMap dedupeSet
+	filters  []*BloomFilter
+	deferred []deferredURL
+}
+
+// deferredURL is an HTTP URL held back until Flush, with its dedupe key.
+type deferredURL struct {
+	result Result
+	key    string
+}
+
+// dedupeSet is the set of keys a Stream has seen.
+type dedupeSet interface {
+	// Add adds key and reports whether it was new.
+	Add(key string) bool
+	Contains(key string) bool
+}
+
+// exactSet is a dedupeSet that never errs.
+type exactSet map[string]bool
+
+func (s exactSet) Add(key string) bool {
+	if s[key] {
+		return false
+	}
+	s[key] = true
+	return true
+}
+
+func (s exactSet) Contains(key string) bool {
+	return s[key]
+}
+
+// NewStream returns a Stream that emits cleaned URLs.
+func (c *Cleaner) NewStream() *Stream {
+	return c.newStream(false)
+}
+
+// NewDomainStream returns a Stream that emits unique domains, like Domains.
+func (c *Cleaner) NewDomainStream() *Stream {
+	return c.newStream(true)
+}
+
+func (c *Cleaner) newStream(domains bool) *Stream {
+	s := &Stream{
+		cleaner:  c,
+		domains:  domains,
+		seen:     exactSet{},
+		httpsMap: exactSet{},
+	}
+	if c.opts.ApproxDedupe {
+		// Each filter holds at most one key per URL, so that both keep the
+		// false-positive rate they are sized for
+		seen := NewBloomFilter(c.opts.ExpectedCount, c.opts.FPRate)
+		s.seen, s.filters = seen, []*BloomFilter{seen}
+		if c.opts.CleanHTTP && !domains {
+			https := NewBloomFilter(c.opts.ExpectedCount, c.opts.FPRate)
+			s.httpsMap, s.filters = https, append(s.filters, https)
+		}
+	}
+	return s
+}
+
+// Filters returns the BloomFilters of an ApproxDedupe stream: the filter of
+// seen URLs followed, with CleanHTTP, by that of HTTPS URLs. It returns nil
+// for other streams.
+func (s *Stream) Filters() []*BloomFilter {
+	return s.filters
+}
+
+// Push processes a single URL. It returns the URL to emit and true, or false
+// if the URL is a duplicate or has been deferred until Flush.
+func (s *Stream) Push(url string) (string, bool) {
+	r, ok := s.push(url)
+	return r.URL, ok
+}
+
+// PushResult is Push returning a Result like CleanResults. RuleCleanHTTP is
+// never reported, as HTTP duplicates are only known at the end of the stream.
+func (s *Stream) PushResult(url string) (Result, bool) {
+	r, ok := s.push(url)
+	if ok {
+		r.complete()
+	}
+	return r, ok
+}
+
+func (s *Stream) push(url string) (Result, bool) {
+	if s.domains {
+		if !s.cleaner.keepsInput(url) {
+			return Result{}, false
+		}
+		domain := s.cleaner.domainExtractor()(strings.Trim(strings.ToLower(url), `"'!`))
+		if domain == "" || !s.seen.Add(domain) {
+			return Result{}, false
+		}
+		return Result{Original: url, URL: domain}, true
+	}
+
+	processedURL, rules := s.cleaner.cleanOne(url)
+	if !s.cleaner.keeps(processedURL) {
+		return Result{}, false
+	}
+
+	// Compare by key but output the original spelling, or the pattern
+	key := s.cleaner.key(processedURL)
+	switch {
+	case s.cleaner.opts.Patterns:
+		processedURL = s.cleaner.pattern(processedURL)
+	case s.cleaner.opts.DedupeKey:
+		processedURL = s.cleaner.originalSpelling(url)
+	}
+	r := Result{Original: url, URL: processedURL, Rules: rules}
+
+	if s.cleaner.opts.CleanHTTP {
+		switch {
+		case strings.HasPrefix(key, "https://"):
+			s.httpsMap.Add(normalizeURLForComparison(key))
+		case strings.HasPrefix(key, "http://") && s.httpsMap.Contains(normalizeURLForComparison(key)):
+			return Result{}, false
+		case strings.HasPrefix(key, "http://") && !s.cleaner.opts.ApproxDedupe:
+			if !s.seen.Add(key) {
+				return Result{}, false
+			}
+			// Defer until the end of the stream: an HTTPS twin may still follow
+			s.deferred = append(s.deferred, deferredURL{result: r, key: key})
+			return Result{}, false
+		}
+	}
+
+	if !s.seen.Add(key) {
+		return Result{}, false
+	}
+	if seen := s.cleaner.opts.Seen; seen != nil && !seen.Add(key) {
+		return Result{}, false
+	}
+	return r, true
+}
+
+// Flush returns the deferred HTTP URLs for which no HTTPS twin was seen.
+// It must be called once, after the last Push.
+func (s *Stream) Flush() []string {
+	var result []string
+	for _, r := range s.flush() {
+		result = append(result, r.URL)
+	}
+	return result
+}
+
+// FlushResults is Flush returning Results like PushResult.
+func (s *Stream) FlushResults() []Result {
+	results := s.flush()
+	for i := range results {
+		results[i].complete()
+	}
+	return results
+}
+
+func (s *Stream) flush() []Result {
+	var result []Result
+	for _, d := range s.deferred {
+		if s.httpsMap.Contains(normalizeURLForComparison(d.key)) {
+			continue
+		}
+		if seen := s.cleaner.opts.Seen; seen == nil || seen.Add(d.key) {
+			result = append(result, d.result)
+		}
+	}
+	s.deferred = nil
+	return result
+}
@@ -0,0 +1,116 @@
+package cleanurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func runStream(s *Stream, urls []string) []string {
+	result := []string{}
+	for _, url := range urls {
+		if out, ok := s.Push(url); ok {
+			result = append(result, out)
+		}
+	}
+	return append(result, s.Flush()...)
+}
+
+func TestStream(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    []string
+		opts     Options
+		expected []string
+	}{
+		{
+			name:     "HTTPS after HTTP drops the deferred HTTP URL",
+			input:    []string{"http://example.com/", "https://example.com"},
+			opts:     DefaultOptions(),
+			expected: []string{"https://example.com"},
+		},
+		{
+			name:     "HTTP without HTTPS twin is emitted at the end",
+			input:    []string{"http://example.com", "https://test.com", "http://example.com"},
+			opts:     DefaultOptions(),
+			expected: []string{"https://test.com", "http://example.com"},
+		},
+		{
+			name:     "HTTP cleaning disabled emits in input order",
+			input:    []string{"http://example.com", "https://example.com"},
+			opts:     Options{Characters: true, Backslash: true, Lower: LowerHost},
+			expected: []string{"http://example.com", "https://example.com"},
+		},
+		{
+			name:     "Ports and special characters",
+			input:    []string{"'https://EXAMPLE.com:8080/path/'", `"http://example.com:9090/another"`, "http://example.com:8080/path"},
+			opts:     DefaultOptions(),
+			expected: []string{"https://example.com:8080/path", "http://example.com:9090/another"},
+		},
+		{
+			name:     "Empty input",
+			input:    []string{},
+			opts:     DefaultOptions(),
+			expected: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runStream(New(tt.opts).NewStream(), tt.input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestStreamMatchesClean(t *testing.T) {
+	input := []string{
+		`"https://example.com/"`,
+		"http://example.com",
+		"https://example.com",
+		"'https://test.com/'",
+		"https://test.com",
+		"http://unique.com",
+		"https://example.com:8080/path",
+		"http://example.com:8080/path",
+	}
+
+	cleaner := New(DefaultOptions())
+	assert.ElementsMatch(t, cleaner.Clean(input), runStream(cleaner.NewStream(), input))
+}
+
+func TestDomainStream(t *testing.T) {
+	input := []string{"https://www.example.com:8080/path", "http://EXAMPLE.com", "'https://test.com'"}
+
+	result := runStream(New(DefaultOptions()).NewDomainStream(), input)
+	assert.Equal(t, []string{"example.com", "test.com"}, result)
+}
+
+func TestStreamWithDedupeKey(t *testing.T) {
+	input := []string{"HTTP://Example.com/A", "https://Example.com/B/", "https://example.com/b", "https://example.com/a"}
+
+	result := runStream(New(Options{CleanHTTP: true, DedupeKey: true}).NewStream(), input)
+	assert.Equal(t, []string{"https://Example.com/B/", "https://example.com/a"}, result)
+
+	result = runStream(New(Options{StripTracking: true, DedupeKey: true}).NewStream(), []string{"https://Example.com/Post?utm_source=x&id=1"})
+	assert.Equal(t, []string{"https://Example.com/Post?id=1"}, result)
+}
+
+func TestStreamResults(t *testing.T) {
+	s := New(DefaultOptions()).NewStream()
+
+	r, ok := s.PushResult("'HTTPS://Example.com/a/'")
+	assert.True(t, ok)
+	assert.Equal(t, "https://example.com/a", r.URL)
+	assert.Equal(t, "'HTTPS://Example.com/a/'", r.Original)
+	assert.Equal(t, "example.com", r.Host)
+	assert.Equal(t, []string{RuleLower, RuleCharacters, RuleBackslash}, r.Rules)
+
+	_, ok = s.PushResult("http://test.com")
+	assert.False(t, ok)
+
+	results := s.FlushResults()
+	assert.Len(t, results, 1)
+	assert.Equal(t, "http://test.com", results[0].URL)
+	assert.Equal(t, []string{}, results[0].Rules)
+}
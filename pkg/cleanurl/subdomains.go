@@ -0,0 +1,67 @@
+package cleanurl
+
+import (
+	"strings"
+)
+
+// SubdomainGroup lists the unique hosts seen under one root domain.
+type SubdomainGroup struct {
+	// Root is the registrable domain, or the Under domain when it is set.
+	Root string
+	// Hosts are the full hostnames below Root, in order of first occurrence.
+	Hosts []string
+}
+
+// Labels returns the hosts of g without the root domain, such as "api" and
+// "dev.api" for api.example.com and dev.api.example.com.
+func (g SubdomainGroup) Labels() []string {
+	labels := make([]string, 0, len(g.Hosts))
+	for _, host := range g.Hosts {
+		labels = append(labels, strings.TrimSuffix(host, "."+g.Root))
+	}
+	return labels
+}
+
+// Subdomains groups the unique hosts found in urls under their registrable
+// domain, in order of first occurrence. Hosts are extracted as in Domains, and
+// a root domain seen on its own is not listed as its own subdomain. URLs
+// dropped by the extension filters, the matchers or the scope are skipped.
+// With Under set, only hosts below that domain are returned, in a single group.
+func (c *Cleaner) Subdomains(urls []string) []SubdomainGroup {
+	extract := c.hostExtractor()
+	under := strings.Trim(strings.ToLower(c.opts.Under), ".")
+
+	groupIndex := make(map[string]int)
+	hostMap := make(map[string]bool)
+	var result []SubdomainGroup
+
+	for _, url := range urls {
+		if !c.keepsInput(url) {
+			continue
+		}
+		host := extract(strings.Trim(strings.ToLower(url), `"'!`))
+		if host == "" || hostMap[host] {
+			continue
+		}
+		hostMap[host] = true
+
+		root := under
+		if under == "" {
+			root = rootDomain(host, c.opts.PrivateSuffixRoots)
+		} else if !strings.HasSuffix(host, "."+under) {
+			continue
+		}
+
+		idx, ok := groupIndex[root]
+		if !ok {
+			idx = len(result)
+			groupIndex[root] = idx
+			result = append(result, SubdomainGroup{Root: root})
+		}
+		if host != root {
+			result[idx].Hosts = append(result[idx].Hosts, host)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,76 @@
+package cleanurl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubdomains(t *testing.T) {
+	input := []string{
+		"https://api.example.com/v1",
+		"https://example.com/",
+		"http://dev.api.example.com:8080/",
+		"https://www.example.com/",
+		"https://API.example.com/v2",
+		"https://shop.example.co.uk/cart",
+		"https://example.co.uk",
+		"https://cdn.test.com/a.js",
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected []SubdomainGroup
+	}{
+		{
+			name: "Grouped by registrable domain",
+			opts: Options{},
+			expected: []SubdomainGroup{
+				{Root: "example.com", Hosts: []string{"api.example.com", "dev.api.example.com"}},
+				{Root: "example.co.uk", Hosts: []string{"shop.example.co.uk"}},
+				{Root: "test.com", Hosts: []string{"cdn.test.com"}},
+			},
+		},
+		{
+			name: "Under a root domain",
+			opts: Options{Under: "example.com"},
+			expected: []SubdomainGroup{
+				{Root: "example.com", Hosts: []string{"api.example.com", "dev.api.example.com"}},
+			},
+		},
+		{
+			name: "Under a subdomain",
+			opts: Options{Under: "API.example.com."},
+			expected: []SubdomainGroup{
+				{Root: "api.example.com", Hosts: []string{"dev.api.example.com"}},
+			},
+		},
+		{
+			name: "Filtered extensions",
+			opts: Options{ExcludeExt: []string{"js"}},
+			expected: []SubdomainGroup{
+				{Root: "example.com", Hosts: []string{"api.example.com", "dev.api.example.com"}},
+				{Root: "example.co.uk", Hosts: []string{"shop.example.co.uk"}},
+			},
+		},
+		{
+			name:     "Under an unseen domain",
+			opts:     Options{Under: "other.com"},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := New(tt.opts).Subdomains(input)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestSubdomainGroupLabels(t *testing.T) {
+	group := SubdomainGroup{Root: "example.com", Hosts: []string{"api.example.com", "dev.api.example.com"}}
+	assert.Equal(t, []string{"api", "dev.api"}, group.Labels())
+	assert.Equal(t, []string{}, SubdomainGroup{Root: "example.com"}.Labels())
+}
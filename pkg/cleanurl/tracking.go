@@ -0,0 +1,156 @@
+package cleanurl
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+//go:embed tracking_params.txt
+var trackingParamsData string
+
+// TrackingRules lists the tracking query parameters removed by StripTracking,
+// both everywhere and on specific domains.
+//
+// Rules are read one per line as a parameter name, optionally preceded by
+// the domain it is limited to, such as "fbclid" or "amazon.com pf_rd_*". A
+// trailing "*" matches any parameter starting with the name, domain rules
+// also apply to subdomains, and matching is case-insensitive. Blank lines and
+// lines starting with "#" are ignored.
+type TrackingRules struct {
+	global  []string
+	domains map[string][]string
+}
+
+var (
+	trackingOnce    sync.Once
+	defaultTracking *TrackingRules
+)
+
+// loadTrackingRules parses the embedded rules on first use.
+func loadTrackingRules() *TrackingRules {
+	trackingOnce.Do(func() {
+		defaultTracking = &TrackingRules{}
+		if err := defaultTracking.Load(strings.NewReader(trackingParamsData)); err != nil {
+			panic(err)
+		}
+	})
+	return defaultTracking
+}
+
+// DefaultTrackingRules returns a copy of the built-in rules, which can be
+// extended with Load.
+func DefaultTrackingRules() *TrackingRules {
+	builtin := loadTrackingRules()
+	rules := &TrackingRules{
+		global:  append([]string{}, builtin.global...),
+		domains: make(map[string][]string, len(builtin.domains)),
+	}
+	for domain, params := range builtin.domains {
+		rules.domains[domain] = append([]string{}, params...)
+	}
+	return rules
+}
+
+// Load adds the rules read from r.
+func (t *TrackingRules) Load(r io.Reader) error {
+	if t.domains == nil {
+		t.domains = make(map[string][]string)
+	}
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(strings.ToLower(line))
+		switch len(fields) {
+		case 1:
+			t.global = append(t.global, fields[0])
+		case 2:
+			domain := strings.TrimPrefix(strings.Trim(fields[0], "."), "www.")
+			t.domains[domain] = append(t.domains[domain], fields[1])
+		default:
+			return fmt.Errorf("tracking rules line %d: want [domain] parameter, got %q", n, line)
+		}
+	}
+	return scanner.Err()
+}
+
+// Strip removes the tracking parameters from the query of raw, keeping the
+// order and encoding of the other parameters. The "?" is dropped with the
+// last parameter. URLs without a query are returned unchanged.
+func (t *TrackingRules) Strip(raw string) string {
+	before, query, after, ok := splitQuery(raw)
+	if !ok {
+		return raw
+	}
+
+	rules := t.rulesFor(extractDomain(strings.ToLower(before)))
+
+	var kept []string
+	for _, param := range strings.Split(query, "&") {
+		if !matchesParam(rules, strings.ToLower(paramName(param))) {
+			kept = append(kept, param)
+		}
+	}
+
+	if len(kept) == 0 {
+		return before + after
+	}
+	return before + "?" + strings.Join(kept, "&") + after
+}
+
+// rulesFor returns the global rules followed by those of host and its parent
+// domains.
+func (t *TrackingRules) rulesFor(host string) []string {
+	rules := t.global
+	for domain := host; domain != ""; {
+		if params, ok := t.domains[domain]; ok {
+			rules = append(rules[:len(rules):len(rules)], params...)
+		}
+		_, parent, found := strings.Cut(domain, ".")
+		if !found {
+			break
+		}
+		domain = parent
+	}
+	return rules
+}
+
+// matchesParam reports whether the lowercase parameter name matches a rule.
+func matchesParam(rules []string, name string) bool {
+	for _, rule := range rules {
+		if prefix, ok := strings.CutSuffix(rule, "*"); ok {
+			if strings.HasPrefix(name, prefix) {
+				return true
+			}
+		} else if name == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// stripTracking is the StripTracking step over a list of URLs.
+func (c *Cleaner) stripTracking(urls []string) []string {
+	rules := c.trackingRules()
+	result := make([]string, 0, len(urls))
+	for _, url := range urls {
+		result = append(result, rules.Strip(url))
+	}
+	return result
+}
+
+// trackingRules returns the configured rules or the built-in ones.
+func (c *Cleaner) trackingRules() *TrackingRules {
+	if c.opts.TrackingRules != nil {
+		return c.opts.TrackingRules
+	}
+	return loadTrackingRules()
+}
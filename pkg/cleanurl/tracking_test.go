@@ -0,0 +1,119 @@
+package cleanurl
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrackingRulesStrip(t *testing.T) {
+	rules := DefaultTrackingRules()
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Campaign parameters",
+			input:    "https://example.com/a?utm_source=x&utm_medium=y&id=1",
+			expected: "https://example.com/a?id=1",
+		},
+		{
+			name:     "Only tracking parameters",
+			input:    "https://example.com/a?fbclid=abc&gclid=def",
+			expected: "https://example.com/a",
+		},
+		{
+			name:     "Order and encoding of other parameters are kept",
+			input:    "https://example.com/?b=2&_hsenc=p2A&a=%20x&mc_eid=1",
+			expected: "https://example.com/?b=2&a=%20x",
+		},
+		{
+			name:     "Case-insensitive and encoded names",
+			input:    "https://example.com/?UTM_Campaign=x&%6D%63_cid=y&q=1",
+			expected: "https://example.com/?q=1",
+		},
+		{
+			name:     "Fragment is kept",
+			input:    "https://example.com/a?utm_source=x#section",
+			expected: "https://example.com/a#section",
+		},
+		{
+			name:     "Domain rule applies to subdomains",
+			input:    "https://www.amazon.com/dp/B01?pf_rd_p=1&ref_=nav&th=1",
+			expected: "https://www.amazon.com/dp/B01?th=1",
+		},
+		{
+			name:     "Domain rule does not apply elsewhere",
+			input:    "https://example.com/?ref_=nav&si=1",
+			expected: "https://example.com/?ref_=nav&si=1",
+		},
+		{
+			name:     "Question mark in fragment",
+			input:    "https://example.com/#a?utm_source=x",
+			expected: "https://example.com/#a?utm_source=x",
+		},
+		{
+			name:     "No query",
+			input:    "https://example.com/a",
+			expected: "https://example.com/a",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, rules.Strip(tt.input))
+		})
+	}
+}
+
+func TestTrackingRulesLoad(t *testing.T) {
+	rules := DefaultTrackingRules()
+	err := rules.Load(strings.NewReader("# custom rules\n\nsessionid\nexample.com  Ref*\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "https://test.com/?a=1", rules.Strip("https://test.com/?sessionid=9&a=1&utm_term=x"))
+	assert.Equal(t, "https://shop.example.com/?a=1", rules.Strip("https://shop.example.com/?referrer=x&a=1&REF=y"))
+	assert.Equal(t, "https://test.com/?ref=y", rules.Strip("https://test.com/?ref=y"))
+
+	// Loading into a copy leaves the built-in rules untouched
+	assert.Equal(t, "https://test.com/?sessionid=9", DefaultTrackingRules().Strip("https://test.com/?sessionid=9"))
+
+	err = rules.Load(strings.NewReader("fbclid\nexample.com ref extra\n"))
+	assert.EqualError(t, err, `tracking rules line 2: want [domain] parameter, got "example.com ref extra"`)
+
+	var empty TrackingRules
+	assert.NoError(t, empty.Load(strings.NewReader("fbclid")))
+	assert.Equal(t, "https://test.com/?utm_source=x", empty.Strip("https://test.com/?utm_source=x&fbclid=1"))
+}
+
+func TestCleanStripTracking(t *testing.T) {
+	opts := DefaultOptions()
+	opts.StripTracking = true
+	cleaner := New(opts)
+
+	input := []string{
+		"https://example.com/page/?utm_source=newsletter",
+		"https://example.com/page?fbclid=abc",
+		"http://example.com/page?gclid=1",
+		"https://example.com/page?id=2&utm_medium=email",
+		"https://example.com/page?id=2",
+	}
+
+	assert.Equal(t, []string{"https://example.com/page", "https://example.com/page?id=2"}, cleaner.Clean(input))
+	assert.Equal(t, "https://example.com/page?id=2", cleaner.CleanOne("https://example.com/page?id=2&utm_medium=email"))
+
+	results := cleaner.CleanResults(input)
+	assert.Equal(t, []string{RuleStripTracking, RuleBackslash, RuleCleanHTTP}, results[0].Rules)
+
+	// Without StripTracking the parameters are kept
+	assert.Len(t, New(DefaultOptions()).Clean(input), 5)
+
+	// Custom rules replace the built-in ones
+	var rules TrackingRules
+	rules.Load(strings.NewReader("id"))
+	opts.TrackingRules = &rules
+	assert.Equal(t, []string{"https://example.com/page/?utm_source=newsletter", "https://example.com/page?fbclid=abc", "http://example.com/page?gclid=1", "https://example.com/page?utm_medium=email", "https://example.com/page"}, New(opts).Clean(input))
+}
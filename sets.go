@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/anatoliyv/cleanurl/pkg/cleanurl"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	// Set command flags
+	showAdded   bool
+	showRemoved bool
+	showCommon  bool
+	noPrefix    bool
+)
+
+// Prefixes of the diff sets, as in a unified diff
+const (
+	removedPrefix = "-"
+	addedPrefix   = "+"
+	commonPrefix  = " "
+)
+
+// rootOnlyFlags are the root flags that do not apply to the set commands:
+// output modes and formats, and options about a single input stream.
+var rootOnlyFlags = map[string]bool{
+	"only-domains":   true,
+	"root-domains":   true,
+	"private-roots":  true,
+	"subdomains":     true,
+	"under":          true,
+	"labels":         true,
+	"format":         true,
+	"columns":        true,
+	"stream":         true,
+	"state":          true,
+	"files-from":     true,
+	"show-rejected":  true,
+	"memory-limit":   true,
+	"approx-dedupe":  true,
+	"fp-rate":        true,
+	"expected-count": true,
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff old.txt new.txt",
+	Short: "Print the URLs removed from and added to a URL list",
+	Long: `Compare two URL lists after cleaning both as cleanurl does, and print the
+URLs only in the first prefixed with "-" and those only in the second prefixed
+with "+". An HTTP URL and its HTTPS twin are the same URL unless --no-clean-http
+is given.`,
+	Example: `  cleanurl diff crawl-monday.txt crawl-tuesday.txt
+  cleanurl diff --added --no-prefix old.txt.gz new.txt.gz`,
+	Args: cobra.ExactArgs(2),
+	Run:  runDiff,
+}
+
+var intersectCmd = &cobra.Command{
+	Use:   "intersect file file...",
+	Short: "Print the URLs found in every list",
+	Long: `Print the URLs found in all the lists, compared after cleaning as in diff, in
+the order and form of the first list.`,
+	Args: cobra.MinimumNArgs(2),
+	Run:  runIntersect,
+}
+
+var unionCmd = &cobra.Command{
+	Use:   "union file...",
+	Short: "Print the URLs found in any list",
+	Long: `Print the URLs found in any of the lists, cleaned and deduplicated across
+all of them as cleanurl does for its input files.`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runUnion,
+}
+
+// addSetCommands adds the set commands to root, sharing its cleaning,
+// filtering and input flags.
+func addSetCommands(root *cobra.Command) {
+	diffCmd.Flags().BoolVar(&showRemoved, "removed", false, "Print the URLs only in the first list")
+	diffCmd.Flags().BoolVar(&showAdded, "added", false, "Print the URLs only in the second list")
+	diffCmd.Flags().BoolVar(&showCommon, "common", false, "Print the URLs in both lists, prefixed with a space")
+	diffCmd.Flags().BoolVar(&noPrefix, "no-prefix", false, "Print URLs without the -, + and space prefixes")
+
+	for _, cmd := range []*cobra.Command{diffCmd, intersectCmd, unionCmd} {
+		root.Flags().VisitAll(func(f *pflag.Flag) {
+			if !rootOnlyFlags[f.Name] {
+				cmd.Flags().AddFlag(f)
+			}
+		})
+		root.AddCommand(cmd)
+	}
+}
+
+func runDiff(cmd *cobra.Command, args []string) {
+	prepareOptions(cmd)
+	lists := readLists(args)
+	comparison := cleanurl.New(opts).Compare(lists[0], lists[1])
+
+	// Without a selection, print what changed
+	if !showRemoved && !showAdded && !showCommon {
+		showRemoved, showAdded = true, true
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	if showRemoved {
+		writeURLs(w, removedPrefix, comparison.Removed)
+	}
+	if showAdded {
+		writeURLs(w, addedPrefix, comparison.Added)
+	}
+	if showCommon {
+		writeURLs(w, commonPrefix, comparison.Common)
+	}
+	if err := w.Flush(); err != nil {
+		fail(err)
+	}
+}
+
+func runIntersect(cmd *cobra.Command, args []string) {
+	prepareOptions(cmd)
+	w := bufio.NewWriter(os.Stdout)
+	writeURLs(w, "", cleanurl.New(opts).Intersect(readLists(args)...))
+	if err := w.Flush(); err != nil {
+		fail(err)
+	}
+}
+
+func runUnion(cmd *cobra.Command, args []string) {
+	prepareOptions(cmd)
+	w := bufio.NewWriter(os.Stdout)
+	writeURLs(w, "", cleanurl.New(opts).Union(readLists(args)...))
+	if err := w.Flush(); err != nil {
+		fail(err)
+	}
+}
+
+// readLists reads the URLs of each input ("-" for stdin) as a separate list.
+func readLists(names []string) [][]string {
+	lists := make([][]string, 0, len(names))
+	for _, name := range names {
+		urls, err := readInputs([]string{name})
+		if err != nil {
+			fail(err)
+		}
+		lists = append(lists, urls)
+	}
+	return lists
+}
+
+// writeURLs prints each URL on its own line after prefix, unless --no-prefix
+// is given.
+func writeURLs(w io.Writer, prefix string, urls []string) {
+	if noPrefix {
+		prefix = ""
+	}
+	for _, url := range urls {
+		fmt.Fprintln(w, prefix+url)
+	}
+}
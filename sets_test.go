@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetCommandFlags(t *testing.T) {
+	for _, cmd := range []string{"diff", "intersect", "union"} {
+		sub, _, err := rootCmd.Find([]string{cmd})
+		assert.NoError(t, err)
+		assert.Equal(t, cmd, sub.Name())
+
+		// Cleaning and filtering flags are shared with the root command
+		for _, name := range []string{"strip-tracking", "no-clean-http", "match", "scope", "extract"} {
+			assert.Same(t, rootCmd.Flags().Lookup(name), sub.Flags().Lookup(name), cmd+" --"+name)
+		}
+		for name := range rootOnlyFlags {
+			assert.Nil(t, sub.Flags().Lookup(name), cmd+" --"+name)
+		}
+	}
+
+	assert.NotNil(t, diffCmd.Flags().Lookup("added"))
+	assert.NotNil(t, diffCmd.Flags().Lookup("removed"))
+	assert.NotNil(t, diffCmd.Flags().Lookup("common"))
+	assert.NotNil(t, diffCmd.Flags().Lookup("no-prefix"))
+
+	// Input files are still accepted by the root command
+	cmd, args, err := rootCmd.Find([]string{"urls.txt"})
+	assert.NoError(t, err)
+	assert.Equal(t, rootCmd, cmd)
+	assert.Equal(t, []string{"urls.txt"}, args)
+}
+
+func TestReadLists(t *testing.T) {
+	dir := t.TempDir()
+	a := writeFile(t, filepath.Join(dir, "a.txt"), []byte("https://a.com\nhttps://b.com\n"))
+	b := writeFile(t, filepath.Join(dir, "b.txt"), []byte("https://c.com\n"))
+
+	assert.Equal(t, [][]string{{"https://a.com", "https://b.com"}, {"https://c.com"}}, readLists([]string{a, b}))
+}
+
+func TestWriteURLs(t *testing.T) {
+	var buf bytes.Buffer
+	writeURLs(&buf, removedPrefix, []string{"https://a.com"})
+	writeURLs(&buf, addedPrefix, []string{"https://b.com", "https://c.com"})
+	writeURLs(&buf, commonPrefix, []string{"https://d.com"})
+	assert.Equal(t, "-https://a.com\n+https://b.com\n+https://c.com\n https://d.com\n", buf.String())
+
+	defer func(p bool) { noPrefix = p }(noPrefix)
+	noPrefix = true
+	buf.Reset()
+	writeURLs(&buf, addedPrefix, []string{"https://b.com"})
+	assert.Equal(t, "https://b.com\n", buf.String())
+}